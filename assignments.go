@@ -0,0 +1,302 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// AssignmentStore records which roles are assigned to each subject, for applications that don't keep roles on their
+// own user models. See EmbedAssignedUser to use a store for permission checks.
+type AssignmentStore interface {
+	// Assign gives a subject a role. Assigning a role the subject already holds does nothing.
+	Assign(ctx context.Context, subjectID, roleID string) error
+
+	// Revoke takes a role from a subject. Revoking a role the subject doesn't hold does nothing.
+	Revoke(ctx context.Context, subjectID, roleID string) error
+
+	// RolesFor lists the IDs of every role assigned to a subject, sorted.
+	RolesFor(ctx context.Context, subjectID string) ([]string, error)
+
+	// SubjectsWith lists the ID of every subject assigned a role, sorted.
+	SubjectsWith(ctx context.Context, roleID string) ([]string, error)
+}
+
+// MemoryStore is an AssignmentStore held in memory, safe for concurrent use.
+type MemoryStore struct {
+	mu          sync.RWMutex
+	assignments map[string]map[string]struct{}
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{assignments: make(map[string]map[string]struct{})}
+}
+
+// Assign gives a subject a role.
+func (s *MemoryStore) Assign(_ context.Context, subjectID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.assign(subjectID, roleID)
+
+	return nil
+}
+
+// Revoke takes a role from a subject.
+func (s *MemoryStore) Revoke(_ context.Context, subjectID, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revoke(subjectID, roleID)
+
+	return nil
+}
+
+// RolesFor lists the IDs of every role assigned to a subject, sorted.
+func (s *MemoryStore) RolesFor(_ context.Context, subjectID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make([]string, 0, len(s.assignments[subjectID]))
+	for roleID := range s.assignments[subjectID] {
+		roles = append(roles, roleID)
+	}
+
+	sort.Strings(roles)
+
+	return roles, nil
+}
+
+// SubjectsWith lists the ID of every subject assigned a role, sorted.
+func (s *MemoryStore) SubjectsWith(_ context.Context, roleID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subjects := make([]string, 0)
+	for subjectID, roles := range s.assignments {
+		if _, ok := roles[roleID]; ok {
+			subjects = append(subjects, subjectID)
+		}
+	}
+
+	sort.Strings(subjects)
+
+	return subjects, nil
+}
+
+// assign and revoke report whether anything changed, and must be called with mu held.
+func (s *MemoryStore) assign(subjectID, roleID string) bool {
+	roles, ok := s.assignments[subjectID]
+	if !ok {
+		roles = make(map[string]struct{})
+		s.assignments[subjectID] = roles
+	}
+
+	if _, ok := roles[roleID]; ok {
+		return false
+	}
+
+	roles[roleID] = struct{}{}
+
+	return true
+}
+
+func (s *MemoryStore) revoke(subjectID, roleID string) bool {
+	roles := s.assignments[subjectID]
+	if _, ok := roles[roleID]; !ok {
+		return false
+	}
+
+	delete(roles, roleID)
+
+	if len(roles) == 0 {
+		delete(s.assignments, subjectID)
+	}
+
+	return true
+}
+
+// FileStore is an AssignmentStore that keeps a JSON file up to date with every change, safe for concurrent use
+// within a process. The file maps each subject ID to its sorted role IDs, e.g. {"assignments": {"42": ["admin"]}}.
+//
+// Changes are written to a temporary file and renamed over the original, so the file is never left half-written.
+// If a change can't be written, it is rolled back and the error returned.
+type FileStore struct {
+	path   string
+	memory *MemoryStore
+}
+
+// fileAssignments is the format of a FileStore's file.
+type fileAssignments struct {
+	Assignments map[string][]string `json:"assignments"`
+}
+
+// OpenFileStore creates a FileStore for the file at path, loading any assignments it already holds.
+// The file is created on the first change if it doesn't exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, memory: NewMemoryStore()}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file fileAssignments
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("rbac: invalid assignments file %s: %w", path, err)
+	}
+
+	for subjectID, roles := range file.Assignments {
+		for _, roleID := range roles {
+			s.memory.assign(subjectID, roleID)
+		}
+	}
+
+	return s, nil
+}
+
+// Assign gives a subject a role, writing the change to disk.
+func (s *FileStore) Assign(_ context.Context, subjectID, roleID string) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if !s.memory.assign(subjectID, roleID) {
+		return nil
+	}
+
+	if err := s.write(); err != nil {
+		s.memory.revoke(subjectID, roleID)
+
+		return err
+	}
+
+	return nil
+}
+
+// Revoke takes a role from a subject, writing the change to disk.
+func (s *FileStore) Revoke(_ context.Context, subjectID, roleID string) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	if !s.memory.revoke(subjectID, roleID) {
+		return nil
+	}
+
+	if err := s.write(); err != nil {
+		s.memory.assign(subjectID, roleID)
+
+		return err
+	}
+
+	return nil
+}
+
+// RolesFor lists the IDs of every role assigned to a subject, sorted.
+func (s *FileStore) RolesFor(ctx context.Context, subjectID string) ([]string, error) {
+	return s.memory.RolesFor(ctx, subjectID)
+}
+
+// SubjectsWith lists the ID of every subject assigned a role, sorted.
+func (s *FileStore) SubjectsWith(ctx context.Context, roleID string) ([]string, error) {
+	return s.memory.SubjectsWith(ctx, roleID)
+}
+
+// write saves every assignment to the file, and must be called with the memory store's lock held.
+func (s *FileStore) write() error {
+	file := fileAssignments{Assignments: make(map[string][]string, len(s.memory.assignments))}
+
+	for subjectID, roles := range s.memory.assignments {
+		ids := make([]string, 0, len(roles))
+		for roleID := range roles {
+			ids = append(ids, roleID)
+		}
+
+		sort.Strings(ids)
+		file.Assignments[subjectID] = ids
+	}
+
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	// the temporary file is only left behind if something failed.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// assignedUser is a values.User whose roles were loaded from an AssignmentStore.
+type assignedUser struct {
+	id    string
+	roles []string
+}
+
+func (u assignedUser) RBACSubjectID() string {
+	return u.id
+}
+
+func (u assignedUser) RBACRoles() []string {
+	return u.roles
+}
+
+// AssignedUser returns a values.User for subjectID, holding the roles assigned to it in store.
+func AssignedUser(ctx context.Context, store AssignmentStore, subjectID string) (values.User, error) {
+	roles, err := store.RolesFor(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return assignedUser{id: subjectID, roles: roles}, nil
+}
+
+// EmbedAssignedUser turns a bare subject ID, set with values.EmbedSubjectID, into a values.User with roles from
+// store, and embeds it in the returned context. If the context has no bare subject ID, it is returned unchanged.
+//
+// Usage: ctx, err := rbac.EmbedAssignedUser(values.EmbedSubjectID(r.Context(), session.UserID), store)
+func EmbedAssignedUser(ctx context.Context, store AssignmentStore) (context.Context, error) {
+	subjectID, ok := values.SubjectIDFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+
+	user, err := AssignedUser(ctx, store, subjectID)
+	if err != nil {
+		return ctx, err
+	}
+
+	return values.Embed(ctx, user), nil
+}
@@ -0,0 +1,88 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/values"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "assignments.json")
+
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+
+	for _, a := range [][2]string{{"42", "viewer"}, {"42", "admin"}, {"7", "viewer"}, {"42", "admin"}} {
+		if err := store.Assign(ctx, a[0], a[1]); err != nil {
+			t.Fatalf("Assign(%s, %s) error = %v", a[0], a[1], err)
+		}
+	}
+
+	if err := store.Revoke(ctx, "7", "viewer"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	// reopening the file should load the same assignments.
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+
+	if roles, _ := store.RolesFor(ctx, "42"); !reflect.DeepEqual(roles, []string{"admin", "viewer"}) {
+		t.Errorf("RolesFor(42) = %v, want [admin viewer]", roles)
+	}
+
+	if subjects, _ := store.SubjectsWith(ctx, "viewer"); !reflect.DeepEqual(subjects, []string{"42"}) {
+		t.Errorf("SubjectsWith(viewer) = %v, want [42]", subjects)
+	}
+
+	// the store should be usable for checks through a bare subject ID.
+	e := NewEnforcer([]Role{{ID: "admin", Permissions: []Permission{{ID: "delete"}}}})
+
+	userCtx, err := EmbedAssignedUser(values.EmbedSubjectID(ctx, "42"), store)
+	if err != nil {
+		t.Fatalf("EmbedAssignedUser() error = %v", err)
+	}
+
+	if !e.Can(userCtx, Permission{ID: "delete"}) {
+		t.Error("Can() = false for a role assigned in the store, want true")
+	}
+}
+
+func TestFileStoreWriteError(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "missing", "assignments.json")
+
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenFileStore() error = %v", err)
+	}
+
+	if err := store.Assign(ctx, "42", "admin"); err == nil {
+		t.Fatal("Assign() error = nil when the file can't be written")
+	}
+
+	// a change that couldn't be written is rolled back.
+	if roles, _ := store.RolesFor(ctx, "42"); len(roles) != 0 {
+		t.Errorf("RolesFor(42) = %v after a failed write, want none", roles)
+	}
+}
+
+func TestOpenFileStoreInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assignments.json")
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFileStore(path); err == nil {
+		t.Error("OpenFileStore() error = nil for an invalid file")
+	}
+}
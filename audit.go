@@ -0,0 +1,294 @@
+package rbac
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+// AuditEvent is a structured record of a single permission check, sent to an AuditSink.
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	SubjectID  string    `json:"subject_id"`
+	Scope      string    `json:"scope,omitempty"`
+	Roles      []string  `json:"roles"`
+	Permission string    `json:"permission"`
+	Subjects   []string  `json:"subjects,omitempty"`
+	Allowed    bool      `json:"allowed"`
+	Reason     Reason    `json:"reason"`
+
+	// Role and Grant describe the grant that allowed or explicitly denied the check, if any.
+	// Grant uses the same syntax as rbac.yaml, e.g. edit_item:self or !delete_item.
+	Role      string `json:"role,omitempty"`
+	Grant     string `json:"grant,omitempty"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// AuditSink receives an AuditEvent for every permission check made by an Enforcer.
+// Record is called synchronously during the check, so implementations must not block, other than briefly.
+type AuditSink interface {
+	Record(event AuditEvent)
+}
+
+// auditSink wraps an AuditSink, so that it can be stored atomically.
+type auditSink struct {
+	sink AuditSink
+}
+
+// WithAuditSink sets the AuditSink that receives an event for every check made by the Enforcer.
+func WithAuditSink(sink AuditSink) Option {
+	return func(e *Enforcer) {
+		e.SetAuditSink(sink)
+	}
+}
+
+// SetAuditSink sets the AuditSink for the default Enforcer. Passing nil disables auditing.
+func SetAuditSink(sink AuditSink) {
+	defaultEnforcer.SetAuditSink(sink)
+}
+
+// SetAuditSink sets the AuditSink that receives an event for every check made by the Enforcer.
+// Passing nil disables auditing.
+func (e *Enforcer) SetAuditSink(sink AuditSink) {
+	if sink == nil {
+		e.audit.Store(nil)
+
+		return
+	}
+
+	e.audit.Store(&auditSink{sink: sink})
+}
+
+// auditing checks if the Enforcer has an AuditSink, in which case every check needs a Decision.
+func (e *Enforcer) auditing() bool {
+	return e.audit.Load() != nil
+}
+
+// record sends a Decision to the Enforcer's AuditSink, if it has one.
+func (e *Enforcer) record(decision Decision) {
+	a := e.audit.Load()
+	if a == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Time:       e.now(),
+		SubjectID:  decision.SubjectID,
+		Scope:      decision.Scope,
+		Roles:      append([]string(nil), decision.RoleIDs...),
+		Permission: decision.Permission,
+		Allowed:    decision.Allowed,
+		Reason:     decision.Reason,
+		Role:       decision.Role,
+	}
+
+	for _, sub := range decision.Subjects {
+		id, err := subject.Convert(sub)
+		if err != nil {
+			id = fmt.Sprintf("<%T>", sub)
+		}
+
+		event.Subjects = append(event.Subjects, id)
+	}
+
+	if decision.Role != "" {
+		event.Grant = formatGrant(decision.Grant)
+		event.Condition = decision.Grant.Condition
+	}
+
+	a.sink.Record(event)
+}
+
+// formatGrant formats a grant in the same syntax as rbac.yaml.
+func formatGrant(p Permission) string {
+	grant := p.ID
+
+	if p.Deny {
+		grant = "!" + grant
+	}
+
+	if len(p.Subjects) > 0 {
+		grant += ":" + strings.Join(p.Subjects, ",")
+	}
+
+	return grant
+}
+
+// JSONLinesSink is an AuditSink that writes each event as a line of JSON.
+//
+// Events are buffered and written from a background goroutine. If the buffer is full, Record waits up to
+// AuditOptions.Wait for room for denied and privileged events, so a slow writer delays those checks rather than
+// losing them, but a stalled one can't hang them. Other events are dropped straight away. Every dropped event is
+// counted by Dropped and passed to AuditOptions.OnDrop.
+type JSONLinesSink struct {
+	events  chan AuditEvent
+	done    chan struct{}
+	w       io.Writer
+	opts    AuditOptions
+	dropped atomic.Uint64
+
+	// mu guards closed, so that Record never sends on a closed channel.
+	mu     sync.RWMutex
+	closed bool
+	once   sync.Once
+	err    error
+}
+
+const (
+	// DefaultAuditBuffer is the number of events a JSONLinesSink buffers, unless AuditOptions.Buffer is set.
+	DefaultAuditBuffer = 1024
+
+	// DefaultAuditWait is how long a JSONLinesSink waits to buffer a denied or privileged event, unless
+	// AuditOptions.Wait is set.
+	DefaultAuditWait = 100 * time.Millisecond
+)
+
+// AuditOptions configures a JSONLinesSink.
+type AuditOptions struct {
+	// Buffer is the number of events buffered before Record waits or drops them. If zero, DefaultAuditBuffer is used.
+	Buffer int
+
+	// Privileged marks allowed events that are waited for like denials, e.g. checks of admin permissions.
+	Privileged func(event AuditEvent) bool
+
+	// Wait is the longest Record waits for room in a full buffer for a denied or privileged event, before dropping it.
+	// If zero, DefaultAuditWait is used.
+	Wait time.Duration
+
+	// OnDrop is called with every event that is dropped, because the buffer was full or the sink was closed, e.g. to
+	// log it or write it somewhere else. It is called during the check, so it must not block.
+	OnDrop func(event AuditEvent)
+}
+
+// NewJSONLinesSink creates a JSONLinesSink writing to w.
+func NewJSONLinesSink(w io.Writer, opts AuditOptions) *JSONLinesSink {
+	if opts.Buffer < 1 {
+		opts.Buffer = DefaultAuditBuffer
+	}
+
+	if opts.Wait <= 0 {
+		opts.Wait = DefaultAuditWait
+	}
+
+	s := &JSONLinesSink{
+		events: make(chan AuditEvent, opts.Buffer),
+		done:   make(chan struct{}),
+		w:      w,
+		opts:   opts,
+	}
+
+	go s.run()
+
+	return s
+}
+
+// OpenAuditFile creates a JSONLinesSink appending to the file at path, creating it if needed.
+func OpenAuditFile(path string, opts AuditOptions) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONLinesSink(f, opts), nil
+}
+
+// Record queues an event to be written. If the buffer is full, denied and privileged events wait up to
+// AuditOptions.Wait for room, and anything else is dropped.
+func (s *JSONLinesSink) Record(event AuditEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.drop(event)
+
+		return
+	}
+
+	select {
+	case s.events <- event:
+		return
+	default:
+	}
+
+	if event.Allowed && (s.opts.Privileged == nil || !s.opts.Privileged(event)) {
+		s.drop(event)
+
+		return
+	}
+
+	timer := time.NewTimer(s.opts.Wait)
+	defer timer.Stop()
+
+	// Close waits for mu, so the channel stays open while the writer makes room.
+	select {
+	case s.events <- event:
+	case <-timer.C:
+		s.drop(event)
+	}
+}
+
+func (s *JSONLinesSink) drop(event AuditEvent) {
+	s.dropped.Add(1)
+
+	if s.opts.OnDrop != nil {
+		s.opts.OnDrop(event)
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was full, or the sink was closed.
+// Each was also passed to OnDrop.
+func (s *JSONLinesSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close writes any buffered events, and closes the underlying writer if it is an io.Closer.
+// It returns the first error encountered while writing.
+func (s *JSONLinesSink) Close() error {
+	s.once.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		close(s.events)
+		s.mu.Unlock()
+
+		<-s.done
+
+		if c, ok := s.w.(io.Closer); ok {
+			s.err = errors.Join(s.err, c.Close())
+		}
+	})
+
+	return s.err
+}
+
+func (s *JSONLinesSink) run() {
+	defer close(s.done)
+
+	buf := bufio.NewWriter(s.w)
+	encoder := json.NewEncoder(buf)
+
+	for event := range s.events {
+		if err := encoder.Encode(event); err != nil && s.err == nil {
+			s.err = err
+		}
+
+		// flush whenever the queue is drained, so events reach the writer promptly.
+		if len(s.events) == 0 {
+			if err := buf.Flush(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+	}
+
+	if err := buf.Flush(); err != nil && s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,171 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// stalledWriter blocks every write until release is closed, and signals each write on started.
+type stalledWriter struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func TestJSONLinesSinkKeepsDenials(t *testing.T) {
+	w := &stalledWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	var dropped []AuditEvent
+
+	sink := NewJSONLinesSink(w, AuditOptions{
+		Buffer: 1,
+		Wait:   time.Minute,
+		Privileged: func(event AuditEvent) bool {
+			return event.Permission == "admin"
+		},
+		OnDrop: func(event AuditEvent) {
+			dropped = append(dropped, event)
+		},
+	})
+
+	// The first event is taken by the writer, which stalls; the second fills the buffer.
+	sink.Record(AuditEvent{Permission: "first", Allowed: true})
+	<-w.started
+	sink.Record(AuditEvent{Permission: "second", Allowed: true})
+	sink.Record(AuditEvent{Permission: "view", Allowed: true})
+
+	if len(dropped) != 1 || dropped[0].Permission != "view" || sink.Dropped() != 1 {
+		t.Fatalf("dropped %v (Dropped() = %d), want only view", dropped, sink.Dropped())
+	}
+
+	recorded := make(chan struct{})
+
+	go func() {
+		sink.Record(AuditEvent{Permission: "delete", Allowed: false})
+		sink.Record(AuditEvent{Permission: "admin", Allowed: true})
+		close(recorded)
+	}()
+
+	select {
+	case <-recorded:
+		t.Fatal("Record() returned for a full buffer without waiting")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(w.release)
+	<-recorded
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	var written []string
+
+	decoder := json.NewDecoder(&w.buf)
+	for decoder.More() {
+		var event AuditEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("decoding %q: %v", w.buf.String(), err)
+		}
+
+		written = append(written, event.Permission)
+	}
+
+	want := []string{"first", "second", "delete", "admin"}
+	if len(written) != len(want) {
+		t.Fatalf("wrote %v, want %v", written, want)
+	}
+
+	for i := range want {
+		if written[i] != want[i] {
+			t.Fatalf("wrote %v, want %v", written, want)
+		}
+	}
+
+	sink.Record(AuditEvent{Permission: "late", Allowed: false})
+
+	if len(dropped) != 2 || dropped[1].Permission != "late" {
+		t.Errorf("dropped %v, want the event recorded after Close", dropped)
+	}
+}
+
+func TestJSONLinesSinkWaitTimeout(t *testing.T) {
+	w := &stalledWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+
+	var dropped []AuditEvent
+
+	sink := NewJSONLinesSink(w, AuditOptions{
+		Buffer: 1,
+		Wait:   10 * time.Millisecond,
+		OnDrop: func(event AuditEvent) {
+			dropped = append(dropped, event)
+		},
+	})
+
+	t.Cleanup(func() {
+		close(w.release)
+		_ = sink.Close()
+	})
+
+	sink.Record(AuditEvent{Permission: "first", Allowed: true})
+	<-w.started
+	sink.Record(AuditEvent{Permission: "second", Allowed: true})
+
+	// a stalled writer only delays a denied check by Wait, after which the event is passed to OnDrop.
+	start := time.Now()
+	sink.Record(AuditEvent{Permission: "delete", Allowed: false})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Record() waited %s, want about 10ms", elapsed)
+	}
+
+	if len(dropped) != 1 || dropped[0].Permission != "delete" || sink.Dropped() != 1 {
+		t.Errorf("dropped %v (Dropped() = %d), want the denied event", dropped, sink.Dropped())
+	}
+}
+
+// auditFunc is an AuditSink calling a function with each event.
+type auditFunc func(AuditEvent)
+
+func (f auditFunc) Record(event AuditEvent) { f(event) }
+
+func TestAuditEventCopiesRoles(t *testing.T) {
+	var events []AuditEvent
+
+	view := Permission{ID: "view"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{view}}}, WithAuditSink(auditFunc(func(event AuditEvent) {
+		events = append(events, event)
+	})))
+
+	roles := []string{"r"}
+	e.Can(values.Embed(context.Background(), testUser{id: "u1", roles: roles}), view)
+
+	// the user's own slice may be reused after the check, while the event is still waiting to be written.
+	roles[0] = "changed"
+
+	if len(events) != 1 || len(events[0].Roles) != 1 || events[0].Roles[0] != "r" {
+		t.Errorf("recorded %+v, want roles [r]", events)
+	}
+}
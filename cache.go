@@ -0,0 +1,170 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// cacheKeyType is an unexported type for storing a decisionCache with context.WithValue.
+type cacheKeyType struct{}
+
+var cacheKey cacheKeyType
+
+// decisionCache memoizes role resolution and decisions for the lifetime of a request.
+// Entries are keyed by the Enforcer and the registry snapshot in use, so replacing roles mid-request is never
+// answered from stale entries.
+type decisionCache struct {
+	mu        sync.Mutex
+	roles     map[rolesKey]resolvedRoles
+	decisions map[decisionKey]Decision
+}
+
+type rolesKey struct {
+	enforcer  *Enforcer
+	registry  *registry
+	subjectID string
+	scope     string
+}
+
+type resolvedRoles struct {
+	ids   []string
+	roles []Role
+}
+
+type decisionKey struct {
+	rolesKey
+	permission string
+	subjects   string
+}
+
+// WithDecisionCache returns a context that memoizes role resolution and permission decisions until it is discarded.
+// It is intended to wrap a single request, so that a user's roles are looked up at most once; it is safe to use
+// from goroutines spawned by the request.
+//
+// Decisions are memoized by permission ID and subject ID. Checks of subjects that expose attributes or owners, such
+// as with RBACAttributes or RBACOwnerID, aren't memoized, as two subjects with the same ID could differ in either.
+func WithDecisionCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheKey, &decisionCache{
+		roles:     make(map[rolesKey]resolvedRoles),
+		decisions: make(map[decisionKey]Decision),
+	})
+}
+
+func decisionCacheFromContext(ctx context.Context) *decisionCache {
+	c, _ := ctx.Value(cacheKey).(*decisionCache)
+
+	return c
+}
+
+func newRolesKey(ctx context.Context, e *Enforcer, reg *registry, user values.User) rolesKey {
+	scope, _ := values.ScopeFromContext(ctx)
+
+	return rolesKey{
+		enforcer:  e,
+		registry:  reg,
+		subjectID: user.RBACSubjectID(),
+		scope:     scope,
+	}
+}
+
+// resolveRoles returns the memoized roles for key, calling resolve to look them up if they haven't been.
+// resolve is called without holding the lock, so a slow lookup doesn't block other checks.
+func (c *decisionCache) resolveRoles(key rolesKey, resolve func() resolvedRoles) resolvedRoles {
+	c.mu.Lock()
+	resolved, ok := c.roles[key]
+	c.mu.Unlock()
+
+	if ok {
+		return resolved
+	}
+
+	resolved = resolve()
+
+	c.mu.Lock()
+	c.roles[key] = resolved
+	c.mu.Unlock()
+
+	return resolved
+}
+
+// decisionKey builds the key for a decision, returning false if it can't be memoized, e.g. without a user.
+func (c *decisionCache) decisionKey(
+	ctx context.Context,
+	e *Enforcer,
+	reg *registry,
+	perm Permission,
+	subjects []any,
+) (decisionKey, bool) {
+	if c == nil {
+		return decisionKey{}, false
+	}
+
+	user := values.FromContext(ctx)
+	if user == nil {
+		return decisionKey{}, false
+	}
+
+	ids := make([]string, 0, len(subjects))
+
+	for _, sub := range subjects {
+		if !memoizable(sub) {
+			return decisionKey{}, false
+		}
+
+		id, err := subject.Convert(sub)
+		if err != nil {
+			return decisionKey{}, false
+		}
+
+		ids = append(ids, id)
+	}
+
+	return decisionKey{
+		rolesKey:   newRolesKey(ctx, e, reg, user),
+		permission: perm.ID,
+		subjects:   strings.Join(ids, "\x00"),
+	}, true
+}
+
+// memoizable checks if a decision for sub only depends on its ID, so it can be memoized by ID.
+// Subjects that expose attributes for conditions, or owners for subject.Self, can differ with the same ID.
+func memoizable(sub any) bool {
+	type attributed interface {
+		RBACAttributes() map[string]any
+	}
+
+	type owner interface {
+		RBACOwnerID() string
+	}
+
+	type owners interface {
+		RBACOwnerIDs() []string
+	}
+
+	switch sub.(type) {
+	case attributed, owner, owners:
+		return false
+	}
+
+	return true
+}
+
+func (c *decisionCache) decision(key decisionKey) (Decision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	decision, ok := c.decisions[key]
+
+	return decision, ok
+}
+
+func (c *decisionCache) storeDecision(key decisionKey, decision Decision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.decisions[key] = decision
+}
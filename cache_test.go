@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// item is a subject with an ID and a status attribute for conditions.
+type item struct {
+	id     string
+	status string
+}
+
+func (i item) RBACSubjectID() string { return i.id }
+
+func (i item) RBACAttributes() map[string]any { return map[string]any{"status": i.status} }
+
+func TestDecisionCacheAttributes(t *testing.T) {
+	edit := Permission{ID: "edit_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{edit.WithCondition("resource.status != 'locked'")}}})
+	ctx := WithDecisionCache(userContext("u1", "r"))
+
+	if !e.Can(ctx, edit, item{"a", "open"}) {
+		t.Error("Can() = false for an open item, want true")
+	}
+
+	if e.Can(ctx, edit, item{"a", "locked"}) {
+		t.Error("Can() = true for a locked item with the same ID, want false")
+	}
+}
+
+// ownedItem is a subject with an ID and an owner.
+type ownedItem struct {
+	id    string
+	owner string
+}
+
+func (i ownedItem) RBACSubjectID() string { return i.id }
+
+func (i ownedItem) RBACOwnerID() string { return i.owner }
+
+func TestDecisionCacheOwners(t *testing.T) {
+	edit := Permission{ID: "edit_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{edit.WithSubjects([]string{subject.Self})}}})
+	ctx := WithDecisionCache(userContext("u1", "r"))
+
+	if !e.Can(ctx, edit, ownedItem{"a", "u1"}) {
+		t.Error("Can() = false for an owned item, want true")
+	}
+
+	if e.Can(ctx, edit, ownedItem{"a", "u2"}) {
+		t.Error("Can() = true for another user's item with the same ID, want false")
+	}
+}
+
+// countingUser counts how often its roles are looked up.
+type countingUser struct {
+	testUser
+	calls *int
+}
+
+func (u countingUser) RBACRoles() []string {
+	*u.calls++
+
+	return u.testUser.RBACRoles()
+}
+
+func TestDecisionCacheMemoizes(t *testing.T) {
+	calls := 0
+	edit := Permission{ID: "edit_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{edit}}})
+	ctx := WithDecisionCache(values.Embed(context.Background(), countingUser{
+		testUser: testUser{id: "u1", roles: []string{"r"}},
+		calls:    &calls,
+	}))
+
+	for i := 0; i < 3; i++ {
+		if !e.Can(ctx, edit, "1") || !e.Can(ctx, edit, item{"2", "open"}) {
+			t.Fatal("Can() = false, want true")
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("looked up roles %d times, want 1", calls)
+	}
+}
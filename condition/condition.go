@@ -0,0 +1,136 @@
+// Package condition implements a small expression language used to attach attribute-based conditions to grants.
+//
+// Expressions compare attributes of the user and of the subject being checked, e.g.
+//
+//	resource.status != 'locked' && resource.team == user.team
+//
+// The language supports:
+// - literals: 'strings' or "strings", numbers, true, false, null, and lists such as ['a', 'b']
+// - attribute paths: user.team, resource.owner.id
+// - comparisons: ==, !=, <, <=, >, >=, and membership with in
+// - logic: &&, || and !, with parentheses for grouping
+//
+// Evaluation is sandboxed: expressions can't call functions or methods, and can only read from the maps they are
+// evaluated against. Comparing an attribute that is missing, or testing it with in, is an error rather than treating
+// it as null, so a condition on an attribute the user or resource doesn't have never passes. Explicitly null
+// attributes compare equal to null. Whole numbers are compared exactly, including IDs too large for a float64.
+package condition
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// MaxLength is the longest expression, in bytes, that Compile accepts.
+	MaxLength = 4096
+
+	// MaxDepth is the deepest nesting of operators and parentheses that Compile accepts.
+	MaxDepth = 32
+)
+
+// Env holds the variables an expression is evaluated against, keyed by root name, e.g. "user" and "resource".
+type Env map[string]map[string]any
+
+// Expression is a compiled condition, safe for concurrent use.
+type Expression struct {
+	source string
+	root   node
+	vars   []string
+}
+
+// Error is returned when an expression can't be compiled or evaluated.
+type Error struct {
+	Source string
+	Pos    int
+	Msg    string
+}
+
+func (err *Error) Error() string {
+	if err.Pos < 0 {
+		return fmt.Sprintf("condition: %s in %q", err.Msg, err.Source)
+	}
+
+	return fmt.Sprintf("condition: %s at offset %d in %q", err.Msg, err.Pos, err.Source)
+}
+
+// Compile parses an expression, returning an error describing the first problem found.
+func Compile(source string) (*Expression, error) {
+	if len(source) > MaxLength {
+		return nil, &Error{Source: source, Pos: -1, Msg: fmt.Sprintf("expression longer than %d bytes", MaxLength)}
+	}
+
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{source: source, tokens: tokens}
+
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{
+		source: source,
+		root:   root,
+		vars:   p.vars,
+	}, nil
+}
+
+// cache holds expressions compiled by Cached, keyed by source.
+var cache sync.Map
+
+// Cached works like Compile, but only compiles each distinct expression once.
+// Expressions that fail to compile are not cached.
+func Cached(source string) (*Expression, error) {
+	if expr, ok := cache.Load(source); ok {
+		return expr.(*Expression), nil
+	}
+
+	expr, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Store(source, expr)
+
+	return expr, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Variables returns the root name of every attribute path in the expression, e.g. "user" for user.team.
+func (e *Expression) Variables() []string {
+	return append([]string(nil), e.vars...)
+}
+
+// Uses checks if the expression refers to any attribute of the named variable, e.g. "user" for user.team.
+func (e *Expression) Uses(name string) bool {
+	for _, v := range e.vars {
+		if v == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Eval evaluates the expression against env. The result must be a boolean, or an error is returned.
+func (e *Expression) Eval(env Env) (bool, error) {
+	v, err := e.root.eval(env)
+	if err != nil {
+		return false, &Error{Source: e.source, Pos: -1, Msg: err.Error()}
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, &Error{Source: e.source, Pos: -1, Msg: fmt.Sprintf("result is %s, not a boolean", typeName(v))}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,183 @@
+package condition
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"user": {
+			"team":  "a",
+			"level": 3,
+			"tags":  []string{"x", "y"},
+			"meta":  map[string]any{"k": "v"},
+		},
+		"resource": {
+			"team":   "a",
+			"status": "open",
+			"size":   int64(10),
+			"meta":   map[string]any{"k": "v"},
+			"owner":  map[string]any{"id": "u1"},
+			"parent": nil,
+			"id":     uint64(1<<63 + 1),
+			"big":    int64(1<<53 + 1),
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"resource.team == user.team", true},
+		{"resource.status != 'locked'", true},
+		{`resource.status == "open"`, true},
+		{"user.level < 5 && resource.size >= 10", true},
+		{"user.level > 3", false},
+		{"resource.owner.id == 'u1'", true},
+		{"resource.parent == null", true},
+		{"'x' in user.tags", true},
+		{"'z' in user.tags", false},
+		{"resource.status in ['open', 'closed']", true},
+		{"'a' in resource.parent", false},
+		{"!(resource.team == user.team)", false},
+		{"!!true", true},
+
+		// && binds tighter than ||.
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"false && true || true", true},
+
+		// short-circuiting skips the right side, which would fail to evaluate.
+		{"false && resource.status", false},
+		{"true || resource.status", true},
+
+		// values that can't be compared are never equal.
+		{"resource.meta == user.meta", false},
+		{"resource.meta != user.meta", true},
+		{"[1, 'a'] == [1, 'a']", true},
+		{"[1] == [1, 2]", false},
+
+		// integers are compared exactly, even where a float64 can't tell them apart.
+		{"resource.big == 9007199254740992", false},
+		{"resource.big == 9007199254740993", true},
+		{"resource.big > 9007199254740992.0", true},
+		{"resource.id == 9223372036854775809", true},
+		{"resource.id > resource.big", true},
+		{"resource.size == 10.0", true},
+		{"user.level < 3.5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+
+			got, err := expr.Eval(env)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	env := Env{"resource": {"status": "open", "size": 10, "meta": map[string]any{}}}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"resource.status", "result is a string, not a boolean"},
+		{"resource.missing", "result is missing, not a boolean"},
+		{"resource.missing == null", "resource.missing is missing"},
+		{"resource.missing != 'locked'", "resource.missing is missing"},
+		{"user.team == resource.team", "user.team is missing"},
+		{"resource.status.deeper == 'a'", "resource.status.deeper is missing"},
+		{"'a' in resource.missing", "resource.missing is missing"},
+		{"resource.missing in ['a']", "resource.missing is missing"},
+		{"'a' in [resource.missing]", "resource.missing is missing"},
+		{"!(resource.missing == 'a')", "resource.missing is missing"},
+		{"1", "result is a number, not a boolean"},
+		{"resource.size && true", "operand of && is a number"},
+		{"!resource.status", "operand of ! is a string"},
+		{"resource.size < 'a'", "can't compare a number < a string"},
+		{"resource.meta < resource.meta", "can't compare"},
+		{"'a' in resource.status", "not a list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+
+			if _, err := expr.Eval(env); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Eval() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{"unterminated string", "resource.status == 'open", "unterminated string"},
+		{"unterminated double-quoted string", `resource.status == "open`, "unterminated string"},
+		{"trailing escape", `resource.status == 'open\`, "unterminated string"},
+		{"empty", "", "unexpected end of expression"},
+		{"bare root", "resource == 1", "must be followed by an attribute"},
+		{"dangling operator", "true &&", "unexpected end of expression"},
+		{"unclosed paren", "(true", "unexpected end of expression"},
+		{"unclosed list", "'a' in ['a'", "unexpected end of expression"},
+		{"unknown character", "a.b # 1", "unexpected character"},
+		{"function call", "user.name()", "unexpected \"(\""},
+		{"chained comparison", "1 < 2 < 3", "unexpected \"<\""},
+		{"too deep", strings.Repeat("(", MaxDepth+1) + "true" + strings.Repeat(")", MaxDepth+1), "nested deeper than"},
+		{"too many nots", strings.Repeat("!", MaxDepth+1) + "true", "nested deeper than"},
+		{"too long", "'" + strings.Repeat("a", MaxLength) + "' == 'a'", "longer than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	nested := strings.Repeat("(", MaxDepth-1) + "true" + strings.Repeat(")", MaxDepth-1)
+
+	expr, err := Compile(nested)
+	if err != nil {
+		t.Fatalf("Compile() error = %v, want nesting up to MaxDepth to compile", err)
+	}
+
+	if got, err := expr.Eval(nil); err != nil || !got {
+		t.Errorf("Eval() = %v, %v, want true", got, err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	expr, err := Compile("resource.team == user.team && resource.status != 'locked'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(expr.Variables(), ","); got != "resource,user" {
+		t.Errorf("Variables() = %s, want resource,user", got)
+	}
+}
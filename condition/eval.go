@@ -0,0 +1,417 @@
+package condition
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type node interface {
+	eval(env Env) (any, error)
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n literalNode) eval(Env) (any, error) {
+	return n.value, nil
+}
+
+type pathNode struct {
+	root string
+	keys []string
+}
+
+// eval walks nested maps for each key in the path. Anything missing along the way evaluates to missing.
+func (n pathNode) eval(env Env) (any, error) {
+	var current any = env[n.root]
+
+	for _, key := range n.keys {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return missing{path: n.String()}, nil
+		}
+
+		if current, ok = m[key]; !ok {
+			return missing{path: n.String()}, nil
+		}
+	}
+
+	return normalize(current), nil
+}
+
+func (n pathNode) String() string {
+	return n.root + "." + strings.Join(n.keys, ".")
+}
+
+// missing is the value of an attribute path that isn't set. Comparing it, or testing it for membership, is an error,
+// so that a condition on an attribute the user or resource doesn't have fails rather than matching another missing
+// attribute, or passing a != check.
+type missing struct {
+	path string
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n listNode) eval(env Env) (any, error) {
+	list := make([]any, 0, len(n.items))
+
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		if m, ok := v.(missing); ok {
+			return nil, m.err()
+		}
+
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n notNode) eval(env Env) (any, error) {
+	b, err := evalBool(n.operand, env, "!")
+	if err != nil {
+		return nil, err
+	}
+
+	return !b, nil
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+// eval short-circuits, so the right side is only evaluated if it decides the result.
+func (n logicalNode) eval(env Env) (any, error) {
+	op := "&&"
+	if n.or {
+		op = "||"
+	}
+
+	left, err := evalBool(n.left, env, op)
+	if err != nil {
+		return nil, err
+	}
+
+	if left == n.or {
+		return left, nil
+	}
+
+	return evalBool(n.right, env, op)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(env Env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := present(left, right); err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	cmp, err := order(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("can't compare %s %s %s", typeName(left), n.op, typeName(right))
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+
+	return cmp >= 0, nil
+}
+
+type inNode struct {
+	left, right node
+}
+
+func (n inNode) eval(env Env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := present(left, right); err != nil {
+		return nil, err
+	}
+
+	switch list := right.(type) {
+	case nil:
+		return false, nil
+
+	case []any:
+		for _, item := range list {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	return nil, fmt.Errorf("right side of in is %s, not a list", typeName(right))
+}
+
+// present returns an error for the first missing operand.
+func present(operands ...any) error {
+	for _, v := range operands {
+		if m, ok := v.(missing); ok {
+			return m.err()
+		}
+	}
+
+	return nil
+}
+
+func (m missing) err() error {
+	return fmt.Errorf("%s is missing", m.path)
+}
+
+func evalBool(n node, env Env, op string) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("operand of %s is %s, not a boolean", op, typeName(v))
+	}
+
+	return b, nil
+}
+
+// normalize converts attribute values to the types expressions work with: nil, bool, int64, uint64, float64, string
+// and []any. Integers are kept as int64, or uint64 if they don't fit, so that large IDs compare exactly.
+// Anything else is returned as-is, and can only be compared for equality.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return normalizeUint(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return normalizeUint(v)
+	case float32:
+		return float64(v)
+
+	case []string:
+		list := make([]any, 0, len(v))
+		for _, s := range v {
+			list = append(list, s)
+		}
+
+		return list
+
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalize(item))
+		}
+
+		return list
+	}
+
+	return v
+}
+
+// normalizeUint returns v as an int64 if it fits, so that each integer has a single representation.
+func normalizeUint(v uint64) any {
+	if v <= math.MaxInt64 {
+		return int64(v)
+	}
+
+	return v
+}
+
+func equal(left, right any) (result bool) {
+	// values that aren't comparable, such as maps, are never equal rather than panicking.
+	defer func() {
+		if recover() != nil {
+			result = false
+		}
+	}()
+
+	l, lok := left.([]any)
+	r, rok := right.([]any)
+
+	if lok || rok {
+		if !lok || !rok || len(l) != len(r) {
+			return false
+		}
+
+		for i := range l {
+			if !equal(l[i], r[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if cmp, ok := compareNumbers(left, right); ok {
+		return cmp == 0
+	}
+
+	return left == right
+}
+
+func order(left, right any) (int, error) {
+	if cmp, ok := compareNumbers(left, right); ok {
+		return cmp, nil
+	}
+
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok {
+			return compare(l < r, l > r), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unordered")
+}
+
+// compareNumbers compares two numbers exactly, whether they are int64, uint64 or float64. It returns false if either
+// isn't a number, or one is NaN.
+func compareNumbers(left, right any) (int, bool) {
+	switch l := left.(type) {
+	case int64:
+		switch r := right.(type) {
+		case int64:
+			return compare(l < r, l > r), true
+		case uint64:
+			// normalize only keeps a uint64 if it is larger than any int64.
+			return -1, true
+		case float64:
+			return compareFloat(l, r, 0x1p63)
+		}
+
+	case uint64:
+		switch r := right.(type) {
+		case int64:
+			return 1, true
+		case uint64:
+			return compare(l < r, l > r), true
+		case float64:
+			return compareFloat(l, r, 0x1p64)
+		}
+
+	case float64:
+		switch r := right.(type) {
+		case int64, uint64:
+			cmp, ok := compareNumbers(r, l)
+
+			return -cmp, ok
+		case float64:
+			if math.IsNaN(l) || math.IsNaN(r) {
+				return 0, false
+			}
+
+			return compare(l < r, l > r), true
+		}
+	}
+
+	return 0, false
+}
+
+// compareFloat compares an integer and a float64 exactly. Converting the integer to a float64 may round it, but only
+// to a neighbouring float, so the conversion decides the result unless the two are equal. Then r is a whole number,
+// which can be converted back unless it is limit, one past the largest value of T.
+func compareFloat[T int64 | uint64](l T, r float64, limit float64) (int, bool) {
+	switch {
+	case math.IsNaN(r):
+		return 0, false
+	case float64(l) < r:
+		return -1, true
+	case float64(l) > r:
+		return 1, true
+	case r >= limit:
+		return -1, true
+	}
+
+	i := T(r)
+
+	return compare(l < i, l > i), true
+}
+
+func compare(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+
+	return 0
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case int64, uint64, float64:
+		return "a number"
+	case missing:
+		return "missing"
+	case string:
+		return "a string"
+	case []any:
+		return "a list"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
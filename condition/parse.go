@@ -0,0 +1,415 @@
+package condition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+// operators are matched longest first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func lex(source string) ([]token, error) {
+	tokens := make([]token, 0)
+
+	for i := 0; i < len(source); {
+		c := source[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'' || c == '"':
+			str, n, err := lexString(source, i)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: source[i : i+n], value: str, pos: i})
+			i += n
+
+		case isDigit(c) || (c == '-' && i+1 < len(source) && isDigit(source[i+1])):
+			start := i
+			i++
+
+			for i < len(source) && (isDigit(source[i]) || source[i] == '.') {
+				i++
+			}
+
+			n, err := parseNumber(source[start:i])
+			if err != nil {
+				return nil, &Error{Source: source, Pos: start, Msg: fmt.Sprintf("invalid number %q", source[start:i])}
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], value: n, pos: start})
+
+		case isIdentStart(c):
+			start := i
+
+			for i < len(source) && isIdent(source[i]) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+
+		default:
+			op := ""
+
+			for _, candidate := range operators {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+
+					break
+				}
+			}
+
+			if op == "" {
+				return nil, &Error{Source: source, Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// lexString reads a quoted string starting at i, returning its value and length including quotes.
+func lexString(source string, i int) (string, int, error) {
+	quote := source[i]
+
+	var b strings.Builder
+
+	for j := i + 1; j < len(source); j++ {
+		switch source[j] {
+		case quote:
+			return b.String(), j - i + 1, nil
+
+		case '\\':
+			if j+1 >= len(source) {
+				break
+			}
+
+			j++
+			b.WriteByte(source[j])
+
+		default:
+			b.WriteByte(source[j])
+		}
+	}
+
+	return "", 0, &Error{Source: source, Pos: i, Msg: "unterminated string"}
+}
+
+// parseNumber parses a number literal, keeping whole numbers as integers so that they compare exactly with attributes.
+func parseNumber(text string) (any, error) {
+	if strings.Contains(text, ".") {
+		return strconv.ParseFloat(text, 64)
+	}
+
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return i, nil
+	}
+
+	if u, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return normalizeUint(u), nil
+	}
+
+	// whole numbers too large for any integer are still valid, if inexact.
+	return strconv.ParseFloat(text, 64)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// parser is a recursive descent parser. In order of precedence, lowest first:
+//
+//	or      = and { "||" and }
+//	and     = compare { "&&" compare }
+//	compare = unary [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) unary ]
+//	unary   = "!" unary | primary
+//	primary = literal | path | "(" or ")" | "[" [ or { "," or } ] "]"
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+	depth  int
+	vars   []string
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == op {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.unexpected(p.peek())
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return &Error{Source: p.source, Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+
+	return &Error{Source: p.source, Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+// enter guards against deeply nested expressions, which could otherwise exhaust the stack.
+func (p *parser) enter() error {
+	p.depth++
+
+	if p.depth > MaxDepth {
+		return &Error{Source: p.source, Pos: p.peek().pos, Msg: fmt.Sprintf("expression nested deeper than %d", MaxDepth)}
+	}
+
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = logicalNode{or: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.compare()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+		right, err := p.compare()
+		if err != nil {
+			return nil, err
+		}
+
+		left = logicalNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+
+	switch {
+	case t.kind == tokenOperator && comparisons[t.text]:
+		p.next()
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return compareNode{op: t.text, left: left, right: right}, nil
+
+	case t.kind == tokenIdent && t.text == "in":
+		p.next()
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return inNode{left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{operand: operand}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString, tokenNumber:
+		return literalNode{value: t.value}, nil
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		case "in":
+			return nil, p.unexpected(t)
+		}
+
+		return p.path(t)
+
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			n, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+
+			return n, p.expect(")")
+
+		case "[":
+			return p.list()
+		}
+	}
+
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) path(root token) (node, error) {
+	path := pathNode{root: root.text}
+
+	for p.accept(".") {
+		t := p.next()
+		if t.kind != tokenIdent {
+			return nil, p.unexpected(t)
+		}
+
+		path.keys = append(path.keys, t.text)
+	}
+
+	if len(path.keys) == 0 {
+		return nil, &Error{Source: p.source, Pos: root.pos, Msg: fmt.Sprintf("%s must be followed by an attribute, e.g. %s.id", root.text, root.text)}
+	}
+
+	seen := false
+	for _, v := range p.vars {
+		seen = seen || v == root.text
+	}
+
+	if !seen {
+		p.vars = append(p.vars, root.text)
+	}
+
+	return path, nil
+}
+
+func (p *parser) list() (node, error) {
+	list := listNode{}
+
+	if p.accept("]") {
+		return list, nil
+	}
+
+	for {
+		item, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		list.items = append(list.items, item)
+
+		if p.accept("]") {
+			return list, nil
+		}
+
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
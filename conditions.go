@@ -0,0 +1,100 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ameliaikeda/rbac/condition"
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// compileCondition compiles a grant's condition, checking that it only refers to the user and resource variables.
+// Conditions are cached once compiled, so registering roles means they are never compiled during a check.
+func compileCondition(expr string) (*condition.Expression, error) {
+	compiled, err := condition.Cached(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range compiled.Variables() {
+		if name != "user" && name != "resource" {
+			return nil, fmt.Errorf("rbac: condition %q refers to %s; only user and resource are available", expr, name)
+		}
+	}
+
+	return compiled, nil
+}
+
+// conditionHolds evaluates the permission's condition against the user in the context and sub, which may be nil.
+// Permissions without a condition always hold. If the condition is invalid, or fails to evaluate, onError is returned.
+func (p Permission) conditionHolds(ctx context.Context, sub any, onError bool) bool {
+	if p.Condition == "" {
+		return true
+	}
+
+	compiled, err := compileCondition(p.Condition)
+	if err != nil {
+		log(ctx, "invalid grant condition", "rbac.permission.id", p.ID, "error", err.Error())
+
+		return onError
+	}
+
+	result, err := compiled.Eval(condition.Env{
+		"user":     userAttributes(ctx),
+		"resource": subject.Attributes(sub),
+	})
+	if err != nil {
+		log(ctx, "grant condition failed", "rbac.permission.id", p.ID, "error", err.Error())
+
+		return onError
+	}
+
+	return result
+}
+
+// needsResource checks if the permission's condition refers to the resource, so that it can only hold for a subject.
+func (p Permission) needsResource() bool {
+	if p.Condition == "" {
+		return false
+	}
+
+	compiled, err := compileCondition(p.Condition)
+
+	return err == nil && compiled.Uses("resource")
+}
+
+// deniesSubjects checks if a deny grant applies to a check of subjects.
+// With no subjects, only deny grants that apply to every subject do, such as those without subjects or listing
+// subject.Wildcard, mirroring the allow grants that check; otherwise the grant applies if any subject matches.
+func (p Permission) deniesSubjects(ctx context.Context, subjects ...any) bool {
+	if len(subjects) == 0 {
+		return p.unscoped() && p.conditionHolds(ctx, nil, true)
+	}
+
+	for _, sub := range subjects {
+		if (len(p.Subjects) == 0 || p.ValidSubject(ctx, sub)) && p.conditionHolds(ctx, sub, true) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// userAttributes returns the attributes of the user in the context, with the user's subject ID available as id.
+func userAttributes(ctx context.Context) map[string]any {
+	user := values.FromContext(ctx)
+	if user == nil {
+		return nil
+	}
+
+	attrs := map[string]any{"id": user.RBACSubjectID()}
+
+	if u, ok := user.(values.AttributedUser); ok {
+		for k, v := range u.RBACAttributes() {
+			attrs[k] = v
+		}
+	}
+
+	return attrs
+}
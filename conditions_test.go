@@ -0,0 +1,101 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// attributed is a subject with an ID and arbitrary attributes.
+type attributed struct {
+	id    string
+	attrs map[string]any
+}
+
+func (a attributed) RBACSubjectID() string { return a.id }
+
+func (a attributed) RBACAttributes() map[string]any { return a.attrs }
+
+func TestConditionMissingAttributes(t *testing.T) {
+	archive := Permission{ID: "items.archive"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{
+		archive.WithCondition("resource.status != 'locked' && resource.team == user.team"),
+	}}})
+
+	withTeam := values.Embed(context.Background(), testUser{id: "u1", roles: []string{"r"}, attrs: map[string]any{"team": "a"}})
+	withoutTeam := userContext("u1", "r")
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		subjects []any
+		want     bool
+	}{
+		{"matching team", withTeam, []any{attributed{"i1", map[string]any{"status": "open", "team": "a"}}}, true},
+		{"other team", withTeam, []any{attributed{"i1", map[string]any{"status": "open", "team": "b"}}}, false},
+		{"user without team", withoutTeam, []any{attributed{"i1", map[string]any{"status": "open"}}}, false},
+		{"resource without team", withTeam, []any{attributed{"i1", map[string]any{"status": "open"}}}, false},
+		{"string subject", withoutTeam, []any{"i1"}, false},
+		{"no subject", withoutTeam, nil, false},
+		{"no subject with team", withTeam, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Can(tt.ctx, archive, tt.subjects...); got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+
+			if got := e.Explain(tt.ctx, archive, tt.subjects...); got.Allowed != tt.want {
+				t.Errorf("Explain() = %v (%s), want %v", got.Allowed, got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionWithoutSubject(t *testing.T) {
+	edit, view := Permission{ID: "edit_item"}, Permission{ID: "view_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{
+		edit.WithCondition("resource.status != 'locked'"),
+		// a condition on the resource can't hold without one, even if it would short-circuit.
+		view.WithCondition("user.id == 'u1' || resource.public == true"),
+	}}})
+
+	ctx := userContext("u2", "r")
+
+	if e.Can(ctx, edit) {
+		t.Error("Can() = true without a subject for a condition on the resource, want false")
+	}
+
+	if got := e.Explain(ctx, edit).Reason; got != ReasonConditionFailed {
+		t.Errorf("Explain() reason = %s, want %s", got, ReasonConditionFailed)
+	}
+
+	if e.Can(userContext("u1", "r"), view) {
+		t.Error("Can() = true without a subject for a condition that refers to the resource, want false")
+	}
+
+	if !e.Can(ctx, edit, attributed{"i1", map[string]any{"status": "open"}}) {
+		t.Error("Can() = false for an open item, want true")
+	}
+}
+
+func TestDenyConditionMissingAttribute(t *testing.T) {
+	del := Permission{ID: "delete_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{
+		del,
+		del.WithDeny().WithCondition("resource.status == 'locked'"),
+	}}})
+
+	ctx := userContext("u1", "r")
+
+	// a deny condition that can't be evaluated applies, rather than letting the allow through.
+	if e.Can(ctx, del, attributed{"i1", nil}) {
+		t.Error("Can() = true for a subject missing the deny condition's attribute, want false")
+	}
+
+	if !e.Can(ctx, del, attributed{"i1", map[string]any{"status": "open"}}) {
+		t.Error("Can() = false for an open item, want true")
+	}
+}
@@ -0,0 +1,264 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// Reason is a short, stable code describing why a Decision was made.
+type Reason string
+
+const (
+	// ReasonGranted means a role held a grant that allowed the permission and every subject.
+	ReasonGranted Reason = "granted"
+
+	// ReasonNoUser means there was no values.User in the context.
+	ReasonNoUser Reason = "no_user"
+
+	// ReasonUnknownRoles means the user had no role IDs that were registered with the Enforcer.
+	ReasonUnknownRoles Reason = "unknown_roles"
+
+	// ReasonNotGranted means none of the user's roles hold the permission.
+	ReasonNotGranted Reason = "not_granted"
+
+	// ReasonDenied means one of the user's roles holds a deny grant for the permission.
+	ReasonDenied Reason = "denied"
+
+	// ReasonSubjectMismatch means a role holds the permission, but not for every subject that was checked, or the
+	// check had no subjects and every grant of the permission is scoped to specific subjects.
+	ReasonSubjectMismatch Reason = "subject_mismatch"
+
+	// ReasonInvalidSubject means a subject couldn't be converted for checking; see subject.Convert.
+	ReasonInvalidSubject Reason = "invalid_subject"
+
+	// ReasonConditionFailed means a role holds the permission for every subject, but a grant's condition didn't hold.
+	ReasonConditionFailed Reason = "condition_failed"
+
+	// ReasonUnknownPermission means the permission wasn't registered, and the Enforcer is in RegistryEnforce mode.
+	ReasonUnknownPermission Reason = "unknown_permission"
+)
+
+// Decision is a structured explanation of a permission check.
+type Decision struct {
+	// Allowed is the result of the check, the same as Can would return.
+	Allowed bool
+
+	// Reason explains why the check was allowed or denied.
+	Reason Reason
+
+	// Permission is the ID of the permission that was checked.
+	Permission string
+
+	// Subjects are the subjects that were checked, as passed to Explain.
+	Subjects []any
+
+	// SubjectID is the ID of the user in the context, from values.User.
+	SubjectID string
+
+	// Scope is the active scope, if one was set with values.WithScope.
+	Scope string
+
+	// RoleIDs are the role IDs the user holds, including any that aren't registered.
+	// Roles from time-bounded grants are only included while the grant is active, and if a scope is active,
+	// only roles for that scope are included.
+	RoleIDs []string
+
+	// Role is the ID of the role that allowed or explicitly denied the check, if any.
+	Role string
+
+	// Grant is the permission on Role that allowed or explicitly denied the check, including its subjects.
+	Grant Permission
+
+	// Rules holds the subject rule that matched each subject, in the order they were checked.
+	// Each rule is subject.Wildcard, subject.Self or a literal subject ID.
+	Rules []string
+
+	// err holds the conversion error for a Decision with ReasonInvalidSubject.
+	err error
+}
+
+// Explain works like Can, but returns a Decision describing how the result was reached.
+//
+// Usage: decision := rbac.Explain(ctx, permissions.SpecificationCreate, spec)
+func Explain(ctx context.Context, perm Permission, subjects ...any) Decision {
+	return defaultEnforcer.Explain(ctx, perm, subjects...)
+}
+
+// Explain works like Can, but returns a Decision describing how the result was reached.
+func (e *Enforcer) Explain(ctx context.Context, perm Permission, subjects ...any) Decision {
+	start := time.Now()
+
+	decision := e.explain(ctx, perm, subjects...)
+	e.observe(decision.Permission, decision.Allowed, time.Since(start))
+	e.record(decision)
+
+	if decision.err != nil {
+		log(ctx, "invalid subject", "rbac.permission.id", decision.Permission, "error", decision.err.Error())
+	}
+
+	log(ctx, "checking permissions",
+		"rbac.permission.id", decision.Permission,
+		"rbac.subject.id", decision.SubjectID,
+		"rbac.role.id", decision.Role,
+		"rbac.reason", decision.Reason,
+		"rbac.result", decision.Allowed)
+
+	return decision
+}
+
+func (e *Enforcer) explain(ctx context.Context, perm Permission, subjects ...any) Decision {
+	reg := e.state.load()
+	cache := decisionCacheFromContext(ctx)
+
+	key, cacheable := cache.decisionKey(ctx, e, reg, perm, subjects)
+	if cacheable {
+		if decision, ok := cache.decision(key); ok {
+			decision.Subjects = subjects
+
+			return decision
+		}
+	}
+
+	decision, roles := e.resolve(ctx, reg, subjects)
+	decision = e.decide(ctx, decision, roles, perm, subjects...)
+
+	if cacheable {
+		cache.storeDecision(key, decision)
+	}
+
+	return decision
+}
+
+// resolve looks up the user in the context and their roles registered in reg.
+// The Decision returned has everything filled in but the permission, and a Reason if the user or roles are missing.
+func (e *Enforcer) resolve(ctx context.Context, reg *registry, subjects []any) (Decision, []Role) {
+	decision := Decision{Subjects: subjects}
+
+	if err := validateSubjects(subjects); err != nil {
+		decision.Reason = ReasonInvalidSubject
+		decision.err = err
+
+		return decision, nil
+	}
+
+	user := User(ctx)
+	if user == nil {
+		decision.Reason = ReasonNoUser
+
+		return decision, nil
+	}
+
+	decision.SubjectID = user.RBACSubjectID()
+	decision.Scope, _ = values.ScopeFromContext(ctx)
+
+	var roles []Role
+	decision.RoleIDs, roles = e.userRoles(ctx, reg, user)
+
+	if len(roles) == 0 {
+		decision.Reason = ReasonUnknownRoles
+	}
+
+	return decision, roles
+}
+
+// decide works like the decide function, but denies checks of unregistered permissions in RegistryEnforce mode.
+func (e *Enforcer) decide(ctx context.Context, decision Decision, roles []Role, perm Permission, subjects ...any) Decision {
+	if e.unregistered(ctx, perm) {
+		decision.Permission = perm.ID
+		decision.Reason = ReasonUnknownPermission
+
+		return decision
+	}
+
+	return decide(ctx, decision, roles, perm, subjects...)
+}
+
+// decide checks perm against roles, completing a Decision returned by resolve.
+func decide(ctx context.Context, decision Decision, roles []Role, perm Permission, subjects ...any) Decision {
+	decision.Permission = perm.ID
+
+	if decision.Reason != "" {
+		return decision
+	}
+
+	// deny grants override allows from any role, so they are checked first.
+	for _, role := range roles {
+		if grant, denied := role.denies(ctx, perm, subjects...); denied {
+			decision.Reason = ReasonDenied
+			decision.Role = role.ID
+			decision.Grant = grant
+
+			return decision
+		}
+	}
+
+	decision.Reason = ReasonNotGranted
+
+	for _, role := range roles {
+		grant, rules, reason := role.explain(ctx, perm, subjects...)
+
+		switch reason {
+		case ReasonGranted:
+			decision.Allowed = true
+			decision.Reason = reason
+			decision.Role = role.ID
+			decision.Grant = grant
+			decision.Rules = rules
+
+			return decision
+
+		case ReasonSubjectMismatch, ReasonConditionFailed:
+			decision.Reason = reason
+		}
+	}
+
+	return decision
+}
+
+// Err converts a Decision to an error, returning nil if the check was allowed.
+//
+// - ErrUnauthenticated is returned if there was no user in the context.
+// - ErrNoRoles is returned if the user holds no registered roles.
+// - An error wrapping ErrUnknownPermission is returned if the permission isn't registered; see RegistryEnforce.
+// - An error wrapping subject.ErrUnsupported is returned if a subject couldn't be converted.
+// - Otherwise, a *ForbiddenError is returned, which matches ErrForbidden with errors.Is.
+func (d Decision) Err() error {
+	switch {
+	case d.Allowed:
+		return nil
+	case d.Reason == ReasonNoUser:
+		return ErrUnauthenticated
+	case d.Reason == ReasonUnknownRoles:
+		return ErrNoRoles
+	case d.Reason == ReasonUnknownPermission:
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, d.Permission)
+	case d.err != nil:
+		return fmt.Errorf("rbac: checking %s: %w", d.Permission, d.err)
+	}
+
+	return &ForbiddenError{
+		Permission: d.Permission,
+		SubjectID:  d.SubjectID,
+		Subjects:   d.Subjects,
+		Reason:     d.Reason,
+	}
+}
+
+// validateSubjects checks every subject can be used in a check, panicking instead if subject.SetStrict is enabled.
+func validateSubjects(subjects []any) error {
+	for _, sub := range subjects {
+		if err := subject.Validate(sub); err != nil {
+			if subject.Strict() {
+				panic(err.Error())
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package rbac
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// Enforcer holds a registry of roles, and checks permissions against it.
+//
+// Most applications only need the default Enforcer, which is used by the package-level functions such as
+// SetDefaultRoles and Can. Separate enforcers are useful when one process serves several sets of roles.
+type Enforcer struct {
+	state          *internalState
+	clock          atomic.Pointer[clock]
+	globalFallback atomic.Bool
+	audit          atomic.Pointer[auditSink]
+	metrics        *Metrics
+	registryMode   atomic.Int32
+}
+
+// Option configures an Enforcer when it is created.
+type Option func(*Enforcer)
+
+// clock wraps the function used to tell the time, so that it can be stored atomically.
+type clock struct {
+	now func() time.Time
+}
+
+// WithClock sets the clock used to check time-bounded role grants; see values.GrantedUser.
+// It defaults to time.Now, and is mostly useful for testing that grants expire.
+func WithClock(now func() time.Time) Option {
+	return func(e *Enforcer) {
+		e.SetClock(now)
+	}
+}
+
+// SetClock sets the clock used by the default Enforcer to check time-bounded role grants. Passing nil uses time.Now.
+func SetClock(now func() time.Time) {
+	defaultEnforcer.SetClock(now)
+}
+
+// SetClock sets the clock used to check time-bounded role grants. Passing nil uses time.Now.
+func (e *Enforcer) SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+
+	e.clock.Store(&clock{now: now})
+}
+
+// now returns the current time from the Enforcer's clock.
+func (e *Enforcer) now() time.Time {
+	return e.clock.Load().now()
+}
+
+// defaultEnforcer backs the package-level functions.
+var defaultEnforcer = NewEnforcer(nil, WithMetrics(defaultMetrics))
+
+// Default returns the Enforcer used by the package-level functions.
+func Default() *Enforcer {
+	return defaultEnforcer
+}
+
+// WithGlobalFallback makes global roles, from values.User's RBACRoles, apply inside a scope as well as the
+// roles for that scope. By default, only roles for the active scope are used; see values.ScopedUser.
+func WithGlobalFallback() Option {
+	return func(e *Enforcer) {
+		e.SetGlobalFallback(true)
+	}
+}
+
+// SetGlobalFallback sets whether global roles apply inside a scope for the default Enforcer; see WithGlobalFallback.
+func SetGlobalFallback(enabled bool) {
+	defaultEnforcer.SetGlobalFallback(enabled)
+}
+
+// SetGlobalFallback sets whether global roles apply inside a scope as well as the roles for that scope.
+func (e *Enforcer) SetGlobalFallback(enabled bool) {
+	e.globalFallback.Store(enabled)
+}
+
+// NewEnforcer creates an Enforcer with its own registry, holding the given roles.
+// It panics under the same conditions as SetRoles.
+func NewEnforcer(roles []Role, opts ...Option) *Enforcer {
+	e := &Enforcer{
+		state:   newState(),
+		metrics: NewMetrics(),
+	}
+
+	e.SetClock(time.Now)
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if len(roles) > 0 {
+		e.SetRoles(roles)
+	}
+
+	return e
+}
+
+// SetRoles registers roles with the Enforcer, resolving any inherited permissions.
+// It works like ReplaceRoles, but panics if role IDs are duplicated, or if inheritance is invalid.
+func (e *Enforcer) SetRoles(roles []Role) {
+	if err := e.ReplaceRoles(roles); err != nil {
+		panic(err.Error())
+	}
+}
+
+// ReplaceRoles swaps every role registered with the Enforcer for roles, atomically.
+// Checks that are in flight see either the old roles or the new ones, never a mix of both.
+// If roles fail ValidateRoles, an error is returned and the current roles are kept. In RegistryEnforce mode, roles
+// must also only grant registered permissions.
+func (e *Enforcer) ReplaceRoles(roles []Role) error {
+	if e.RegistryMode() == RegistryEnforce {
+		if err := validateGrants(roles); err != nil {
+			return err
+		}
+	}
+
+	return e.state.setRoles(roles)
+}
+
+// Can uses the current context values to determine if an action can be taken.
+// A deny grant on any of the user's roles overrides allows from every other role.
+func (e *Enforcer) Can(ctx context.Context, perm Permission, subjects ...any) bool {
+	// a Decision is only needed for logging, auditing or memoizing, so skip building one when none would happen.
+	// subjects are copied as a Decision holds on to them, which would otherwise move every call's subjects to the heap.
+	if logr.FromContextOrDiscard(ctx).Enabled() || e.auditing() || decisionCacheFromContext(ctx) != nil {
+		return e.Explain(ctx, perm, append([]any(nil), subjects...)...).Allowed
+	}
+
+	start := time.Now()
+
+	user := values.FromContext(ctx)
+	allowed := !e.unregistered(ctx, perm) && user != nil && validateSubjects(subjects) == nil &&
+		e.state.can(ctx, e.roleIDs(ctx, user), perm, subjects...)
+
+	e.observe(perm.ID, allowed, time.Since(start))
+
+	return allowed
+}
+
+// Roles returns the registered roles held by the user in the current context.
+func (e *Enforcer) Roles(ctx context.Context) []Role {
+	if user := User(ctx); user != nil {
+		_, roles := e.userRoles(ctx, e.state.load(), user)
+
+		if len(roles) == 0 {
+			log(ctx, "no roles present on subject", "rbac.subject.id", user.RBACSubjectID())
+		}
+
+		return roles
+	}
+
+	return nil
+}
+
+// RoleByID returns the role registered with the Enforcer with the given ID, with its inherited permissions resolved.
+func (e *Enforcer) RoleByID(id string) (Role, bool) {
+	role, ok := e.state.load().roleMap[id]
+
+	return role, ok
+}
+
+// userRoles returns the IDs of every role the user holds, and those that are registered in reg.
+// They are memoized if the context was wrapped with WithDecisionCache.
+func (e *Enforcer) userRoles(ctx context.Context, reg *registry, user values.User) ([]string, []Role) {
+	cache := decisionCacheFromContext(ctx)
+	if cache == nil {
+		ids := e.roleIDs(ctx, user)
+
+		return ids, reg.rolesByID(ids)
+	}
+
+	resolved := cache.resolveRoles(newRolesKey(ctx, e, reg, user), func() resolvedRoles {
+		ids := e.roleIDs(ctx, user)
+
+		return resolvedRoles{ids: ids, roles: reg.rolesByID(ids)}
+	})
+
+	return resolved.ids, resolved.roles
+}
+
+// roleIDs returns the IDs of every role the user holds, including those from grants that are currently active.
+//
+// If a scope is active and the user implements values.ScopedUser, only roles for that scope are used, along with global
+// roles if the Enforcer falls back to them. Users that don't implement values.ScopedUser always use their global roles.
+func (e *Enforcer) roleIDs(ctx context.Context, user values.User) []string {
+	scope, inScope := values.ScopeFromContext(ctx)
+	scopedUser, hasScopes := user.(values.ScopedUser)
+	granted, hasGrants := user.(values.GrantedUser)
+
+	global := !inScope || !hasScopes || e.globalFallback.Load()
+
+	// avoid allocating for the common cases, where roles come from a single method on the user.
+	if !hasGrants {
+		switch {
+		case !inScope || !hasScopes:
+			return user.RBACRoles()
+		case !global:
+			return scopedUser.RBACScopedRoles(scope)
+		}
+	}
+
+	ids := make([]string, 0)
+
+	if global {
+		ids = append(ids, user.RBACRoles()...)
+	}
+
+	if inScope && hasScopes {
+		ids = append(ids, scopedUser.RBACScopedRoles(scope)...)
+	}
+
+	if !hasGrants {
+		return ids
+	}
+
+	now := e.now()
+
+	for _, grant := range granted.RBACRoleGrants() {
+		applies := (grant.Scope == "" && global) || (inScope && grant.Scope == scope)
+
+		if applies && grant.Active(now) {
+			ids = append(ids, grant.RoleID)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,145 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+var sizes = []int{10, 100, 1000}
+
+// benchmarkEnforcer creates an Enforcer with n roles of n permissions each, held by a single user.
+// The permission returned is only granted, scoped to subject.Self, by the last role.
+func benchmarkEnforcer(n int) (*Enforcer, context.Context, Permission) {
+	perms := make([]Permission, n)
+	for i := range perms {
+		perms[i] = Permission{ID: fmt.Sprintf("perm.%d", i)}
+	}
+
+	target := Permission{ID: "target"}
+
+	roles := make([]Role, n)
+	ids := make([]string, n)
+
+	for i := range roles {
+		roles[i] = Role{ID: fmt.Sprintf("role-%d", i), Permissions: perms}
+		ids[i] = roles[i].ID
+	}
+
+	roles[n-1].Permissions = append([]Permission{target.WithSubjects([]string{subject.Self})}, perms...)
+
+	return NewEnforcer(roles), userContext("42", ids...), target
+}
+
+func BenchmarkCan(b *testing.B) {
+	for _, n := range sizes {
+		e, ctx, perm := benchmarkEnforcer(n)
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if !e.Can(ctx, perm, "42") {
+					b.Fatal("Can() = false, want true")
+				}
+			}
+		})
+	}
+}
+
+func TestCanAllocs(t *testing.T) {
+	for _, n := range sizes {
+		e, ctx, perm := benchmarkEnforcer(n)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if !e.Can(ctx, perm, "42") || e.Can(ctx, perm, "43") {
+				t.Fatal("Can() gave the wrong result")
+			}
+		})
+
+		if allocs != 0 {
+			t.Errorf("Can() with %d roles made %v allocations, want 0", n, allocs)
+		}
+	}
+}
+
+// grantedUser holds a single time-bounded role grant.
+type grantedUser struct {
+	testUser
+	grant values.Grant
+}
+
+func (u grantedUser) RBACRoleGrants() []values.Grant { return []values.Grant{u.grant} }
+
+func TestSetClock(t *testing.T) {
+	view := Permission{ID: "view"}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	SetDefaultRoles([]Role{{ID: "r", Permissions: []Permission{view}}})
+	t.Cleanup(func() {
+		SetDefaultRoles(nil)
+		SetClock(nil)
+	})
+
+	ctx := values.Embed(context.Background(), grantedUser{
+		testUser: testUser{id: "u1"},
+		grant:    values.Grant{RoleID: "r", NotAfter: start.Add(time.Hour)},
+	})
+
+	SetClock(func() time.Time { return start })
+
+	if !Can(ctx, view) {
+		t.Error("Can() = false while the grant is active, want true")
+	}
+
+	SetClock(func() time.Time { return start.Add(2 * time.Hour) })
+
+	if Can(ctx, view) {
+		t.Error("Can() = true after the grant expired, want false")
+	}
+}
+
+// scopedUser holds global roles, and roles for a single scope.
+type scopedUser struct {
+	testUser
+	scope string
+	roles []string
+}
+
+func (u scopedUser) RBACScopedRoles(scope string) []string {
+	if scope == u.scope {
+		return u.roles
+	}
+
+	return nil
+}
+
+func TestSetGlobalFallback(t *testing.T) {
+	view := Permission{ID: "view"}
+
+	SetDefaultRoles([]Role{{ID: "global", Permissions: []Permission{view}}, {ID: "tenant"}})
+	t.Cleanup(func() {
+		SetDefaultRoles(nil)
+		SetGlobalFallback(false)
+	})
+
+	ctx := values.WithScope(values.Embed(context.Background(), scopedUser{
+		testUser: testUser{id: "u1", roles: []string{"global"}},
+		scope:    "a",
+		roles:    []string{"tenant"},
+	}), "a")
+
+	if Can(ctx, view) {
+		t.Error("Can() = true from a global role inside a scope, want false")
+	}
+
+	SetGlobalFallback(true)
+
+	if !Can(ctx, view) {
+		t.Error("Can() = false with global fallback, want true")
+	}
+}
@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthenticated is returned by Authorize when there is no user in the context.
+	ErrUnauthenticated = errors.New("rbac: no user in context")
+
+	// ErrNoRoles is returned by Authorize when the user holds no registered roles.
+	ErrNoRoles = errors.New("rbac: user has no registered roles")
+
+	// ErrUnknownPermission is wrapped by the error Authorize returns when a permission isn't registered, and the
+	// Enforcer is in RegistryEnforce mode.
+	ErrUnknownPermission = errors.New("rbac: unknown permission")
+
+	// ErrForbidden is matched by every *ForbiddenError when using errors.Is.
+	ErrForbidden = errors.New("rbac: forbidden")
+)
+
+// ForbiddenError is returned by Authorize when a user is authenticated, but not allowed to take an action.
+type ForbiddenError struct {
+	// Permission is the ID of the permission that was checked.
+	Permission string
+
+	// SubjectID is the ID of the user in the context.
+	SubjectID string
+
+	// Subjects are the subjects that were checked, if any.
+	Subjects []any
+
+	// Reason explains why the check was denied.
+	Reason Reason
+}
+
+func (err *ForbiddenError) Error() string {
+	if len(err.Subjects) > 0 {
+		return fmt.Sprintf("rbac: subject %s is not allowed %s on %v (%s)", err.SubjectID, err.Permission, err.Subjects, err.Reason)
+	}
+
+	return fmt.Sprintf("rbac: subject %s is not allowed %s (%s)", err.SubjectID, err.Permission, err.Reason)
+}
+
+// Is allows errors.Is(err, ErrForbidden) to match any *ForbiddenError.
+func (err *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// Authorize works like Can, but returns an error describing why a check was denied, or nil if it was allowed.
+//
+// Usage: if err := rbac.Authorize(ctx, permissions.SpecificationCreate); err != nil { return err }
+func Authorize(ctx context.Context, perm Permission, subjects ...any) error {
+	return defaultEnforcer.Authorize(ctx, perm, subjects...)
+}
+
+// Authorize works like Can, but returns an error describing why a check was denied, or nil if it was allowed.
+// See Decision.Err for the errors that can be returned.
+func (e *Enforcer) Authorize(ctx context.Context, perm Permission, subjects ...any) error {
+	return e.Explain(ctx, perm, subjects...).Err()
+}
@@ -0,0 +1,80 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+func TestAuthorize(t *testing.T) {
+	view, edit, del := Permission{ID: "view"}, Permission{ID: "edit"}, Permission{ID: "delete"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{
+		view,
+		edit.WithSubjects([]string{subject.Self}),
+		del,
+		del.WithDeny(),
+	}}})
+
+	strict := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{registryView}}}, WithRegistryMode(RegistryEnforce))
+
+	tests := []struct {
+		name     string
+		e        *Enforcer
+		ctx      context.Context
+		perm     Permission
+		subjects []any
+		is       error
+		reason   Reason
+	}{
+		{name: "allowed", e: e, ctx: userContext("u1", "r"), perm: view},
+		{name: "allowed subject", e: e, ctx: userContext("u1", "r"), perm: edit, subjects: []any{"u1"}},
+		{name: "no user", e: e, ctx: context.Background(), perm: view, is: ErrUnauthenticated},
+		{name: "no roles", e: e, ctx: userContext("u1", "unknown"), perm: view, is: ErrNoRoles},
+		{name: "unknown permission", e: strict, ctx: userContext("u1", "r"), perm: Permission{ID: "errors_test.unknown"}, is: ErrUnknownPermission},
+		{name: "unsupported subject", e: e, ctx: userContext("u1", "r"), perm: edit, subjects: []any{[]int{1}}, is: subject.ErrUnsupported},
+		{name: "not granted", e: e, ctx: userContext("u1", "r"), perm: Permission{ID: "other"}, is: ErrForbidden, reason: ReasonNotGranted},
+		{name: "denied", e: e, ctx: userContext("u1", "r"), perm: del, is: ErrForbidden, reason: ReasonDenied},
+		{name: "subject mismatch", e: e, ctx: userContext("u1", "r"), perm: edit, subjects: []any{"u2"}, is: ErrForbidden, reason: ReasonSubjectMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.e.Authorize(tt.ctx, tt.perm, tt.subjects...)
+
+			if tt.is == nil {
+				if err != nil {
+					t.Errorf("Authorize() error = %v, want nil", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tt.is) {
+				t.Fatalf("Authorize() error = %v, want one matching %v", err, tt.is)
+			}
+
+			var forbidden *ForbiddenError
+			if got := errors.As(err, &forbidden); got != (tt.reason != "") {
+				t.Fatalf("errors.As(*ForbiddenError) = %v for %v", got, err)
+			}
+
+			if forbidden == nil {
+				if errors.Is(err, ErrForbidden) {
+					t.Errorf("Authorize() error = %v matches ErrForbidden, want it not to", err)
+				}
+
+				return
+			}
+
+			if forbidden.Reason != tt.reason || forbidden.Permission != tt.perm.ID || forbidden.SubjectID != "u1" {
+				t.Errorf("ForbiddenError = %+v, want reason %s for %s", forbidden, tt.reason, tt.perm.ID)
+			}
+
+			if len(forbidden.Subjects) != len(tt.subjects) {
+				t.Errorf("ForbiddenError.Subjects = %v, want %v", forbidden.Subjects, tt.subjects)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"golang.org/x/tools/go/packages"
 
+	"github.com/ameliaikeda/rbac"
 	"github.com/ameliaikeda/rbac/generator/core"
 )
 
@@ -91,7 +92,20 @@ func validate(gen *core.Generator) error {
 		return missing("config.roles.import-path")
 	}
 
-	return nil
+	roles := make([]rbac.Role, 0, len(gen.Roles))
+	for _, role := range gen.Roles {
+		r := role.Role
+		r.Permissions = make([]rbac.Permission, 0, len(role.Permissions))
+
+		for _, perm := range role.Permissions {
+			r.Permissions = append(r.Permissions, perm.Permission)
+		}
+
+		roles = append(roles, r)
+	}
+
+	// catches duplicate IDs, inheritance cycles and invalid conditions before they panic at runtime.
+	return rbac.ValidateRoles(roles)
 }
 
 func ImportPath(path string) (string, error) {
@@ -2,8 +2,8 @@ package yaml
 
 import (
 	"context"
+	"fmt"
 	"os"
-	"strings"
 
 	"github.com/iancoleman/strcase"
 	"gopkg.in/yaml.v3"
@@ -11,7 +11,6 @@ import (
 	"github.com/ameliaikeda/rbac"
 	"github.com/ameliaikeda/rbac/generator/core"
 	"github.com/ameliaikeda/rbac/generator/mapping"
-	"github.com/ameliaikeda/rbac/subject"
 )
 
 // Config is the primary struct used to decode the configuration JSON file.
@@ -19,6 +18,10 @@ type Config struct {
 	Metadata    Metadata              `yaml:"config"`
 	Roles       map[string]Role       `yaml:"roles"`
 	Permissions map[string]Permission `yaml:"permissions"`
+
+	// Markers lists the names of subject markers registered at runtime with subject.RegisterMarker, such as team.
+	// Only declared markers, self and any can be used in place of a subject, e.g. edit_item:team.
+	Markers []string `yaml:"markers"`
 }
 
 // Metadata is used to alter role generation.
@@ -29,11 +32,14 @@ type Metadata struct {
 
 // Role holds all configuration info for a role.
 type Role struct {
-	ID          string   `yaml:"id"`
-	Key         string   `yaml:"-"`
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	Permissions []string `yaml:"permissions"`
+	ID          string  `yaml:"id"`
+	Key         string  `yaml:"-"`
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Permissions []Grant `yaml:"permissions"`
+
+	// Inherits lists the keys of other roles whose permissions this role is also granted.
+	Inherits []string `yaml:"inherits"`
 
 	// extra params
 
@@ -46,6 +52,9 @@ type Role struct {
 	ActiveDirectory string `yaml:"ad-mapping"`
 }
 
+// Grant is a single entry in a role's permissions; it is shared with rbac.LoadPolicy.
+type Grant = rbac.Grant
+
 // Permission holds the configuration info for all permissions.
 // NB: This may be removed later and worked out automatically from Role.
 type Permission struct {
@@ -102,9 +111,21 @@ func OptionsFromYAML(filename string) core.OptionFunc {
 
 		gen.Permissions = perms
 
+		roleIDs := rbac.RoleIDs(config.Roles, func(role Role) string { return role.ID })
+
+		markers := make(map[string]bool, len(config.Markers))
+		for _, name := range config.Markers {
+			markers[name] = true
+		}
+
 		roles := make([]core.Role, 0, len(config.Roles))
 		for id, role := range config.Roles {
-			roles = append(roles, marshalRole(id, role, permsMap))
+			r, err := marshalRole(id, role, permsMap, roleIDs, markers)
+			if err != nil {
+				return err
+			}
+
+			roles = append(roles, r)
 		}
 
 		gen.Roles = roles
@@ -135,7 +156,13 @@ func mergeMetadata(meta *core.TemplateMetadata, add *core.TemplateMetadata) {
 	}
 }
 
-func marshalRole(key string, role Role, permissions map[string]core.Permission) core.Role {
+func marshalRole(
+	key string,
+	role Role,
+	permissions map[string]core.Permission,
+	roleIDs map[string]string,
+	markers map[string]bool,
+) (core.Role, error) {
 	// if key is blank, use the ID.
 	if role.Key == "" {
 		role.Key = key
@@ -153,6 +180,7 @@ func marshalRole(key string, role Role, permissions map[string]core.Permission)
 		ID:          role.ID,
 		Name:        role.Name,
 		Description: role.Description,
+		Inherits:    rbac.InheritedIDs(role.Inherits, roleIDs),
 		CustomMappings: map[string]string{
 			mapping.ActiveDirectoryGroupName: role.ActiveDirectory,
 		},
@@ -163,48 +191,47 @@ func marshalRole(key string, role Role, permissions map[string]core.Permission)
 		GoName: role.GoName,
 	}
 
-	return marshalRolePermissions(role, coreRole, permissions)
+	return marshalRolePermissions(role, coreRole, permissions, markers)
 }
 
-func marshalRolePermissions(template Role, role core.Role, permissions map[string]core.Permission) core.Role {
-	perms := make([]core.Permission, 0, len(template.Permissions))
-	for _, name := range template.Permissions {
-		name, subjects := parseRolePermission(name)
-
-		if p, ok := permissions[name]; ok {
-			p.Subjects = subjects
-
-			perms = append(perms, p)
-		}
+func marshalRolePermissions(
+	template Role,
+	role core.Role,
+	permissions map[string]core.Permission,
+	markers map[string]bool,
+) (core.Role, error) {
+	declared := make(map[string]rbac.Permission, len(permissions))
+	for key, p := range permissions {
+		declared[key] = p.Permission
 	}
 
-	role.Permissions = perms
+	perms := make([]core.Permission, 0, len(template.Permissions))
+	for _, grant := range template.Permissions {
+		parsed, err := rbac.ParseGrant(grant.Permission, markers)
+		if err != nil {
+			return role, fmt.Errorf("rbac: role %s: %w", role.ID, err)
+		}
 
-	return role
-}
+		if err := rbac.CheckGrant(role.ID, parsed, declared); err != nil {
+			return role, err
+		}
 
-func parseRolePermission(name string) (string, []string) {
-	if strings.Contains(name, ":") {
-		// we are dealing with a subject permission, e.g. - edit:self, create:*
-		contents := strings.SplitN(name, ":", 2)
-
-		subjects := make([]string, 0)
-
-		for _, sub := range strings.Split(contents[1], ",") {
-			switch sub {
-			case subject.Wildcard, "any":
-				subjects = append(subjects, subject.Wildcard)
-			case subject.Self, "self":
-				subjects = append(subjects, subject.Self)
-			default:
-				subjects = append(subjects, sub)
-			}
+		p, ok := permissions[parsed.ID]
+		if !ok {
+			// a wildcard grant, which CheckGrant has checked covers a declared permission.
+			p = core.Permission{Permission: parsed}
 		}
 
-		return contents[0], subjects
+		p.Subjects = parsed.Subjects
+		p.Deny = parsed.Deny
+		p.Condition = grant.Condition
+
+		perms = append(perms, p)
 	}
 
-	return name, nil
+	role.Permissions = perms
+
+	return role, nil
 }
 
 func marshalPermission(key string, permission Permission) core.Permission {
@@ -0,0 +1,56 @@
+package yaml
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/generator/core"
+)
+
+func TestOptionsFromYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "undeclared deny",
+			config: "permissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - \"!delete_item\"\n",
+			want:   "not a declared permission",
+		},
+		{
+			name:   "unquoted deny",
+			config: "permissions: {delete_item: {}}\nroles:\n  r:\n    permissions:\n      - !delete_item\n",
+			want:   "quote it",
+		},
+		{
+			name:   "misspelt marker",
+			config: "markers: [team]\npermissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - edit_item:rbac.teem\n",
+			want:   "unknown subject marker teem",
+		},
+		{
+			name:   "empty grant",
+			config: "permissions: {delete_item: {}}\nroles:\n  r:\n    permissions:\n      - \":deny\"\n",
+			want:   "has no permission",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "rbac.yaml")
+			if err := os.WriteFile(filename, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			gen := &core.Generator{PermissionMetadata: &core.TemplateMetadata{}, RoleMetadata: &core.TemplateMetadata{}}
+
+			err := OptionsFromYAML(filename)(context.Background(), gen)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("OptionsFromYAML() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+// ValidateRoles checks that a set of roles can be registered: IDs must be unique, every role listed in
+// Inherits must exist without forming a cycle, and every grant's condition and subject pattern must compile.
+func ValidateRoles(roles []Role) error {
+	_, err := resolveRoles(roles)
+
+	return err
+}
+
+// resolveRoles validates roles and returns them keyed by ID, with inherited permissions resolved.
+func resolveRoles(roles []Role) (map[string]Role, error) {
+	byID := make(map[string]Role, len(roles))
+
+	for _, role := range roles {
+		// don't allow duplicates because of undefined behavior.
+		if _, exists := byID[role.ID]; exists {
+			return nil, fmt.Errorf("rbac: duplicate role ID: %s (%s)", role.ID, role.Name)
+		}
+
+		byID[role.ID] = role
+	}
+
+	resolved := make(map[string]Role, len(roles))
+
+	var visit func(id string, path []string) ([]Permission, error)
+	visit = func(id string, path []string) ([]Permission, error) {
+		if role, ok := resolved[id]; ok {
+			return role.effective, nil
+		}
+
+		for i, seen := range path {
+			if seen == id {
+				cycle := append(path[i:len(path):len(path)], id)
+
+				return nil, fmt.Errorf("rbac: role inheritance cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		role, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("rbac: role %s inherits unknown role %s", path[len(path)-1], id)
+		}
+
+		path = append(path, id)
+		effective := role.Permissions
+
+		if len(role.Inherits) > 0 {
+			effective = append(make([]Permission, 0, len(role.Permissions)), role.Permissions...)
+
+			for _, parent := range role.Inherits {
+				inherited, err := visit(parent, path)
+				if err != nil {
+					return nil, err
+				}
+
+				effective = append(effective, inherited...)
+			}
+		}
+
+		if effective == nil {
+			effective = []Permission{}
+		}
+
+		role.effective = effective
+		role.index = make(map[string][]Permission, len(effective))
+		role.wildcards = make(map[string][]Permission)
+
+		for _, p := range effective {
+			if p.Condition != "" {
+				if _, err := compileCondition(p.Condition); err != nil {
+					return nil, fmt.Errorf("rbac: role %s grants %s with an invalid condition: %w", role.ID, p.ID, err)
+				}
+			}
+
+			for _, sub := range p.Subjects {
+				if !subject.IsPattern(sub) {
+					continue
+				}
+
+				// patterns are cached once compiled, so they are never compiled during a check.
+				if _, err := subject.CachedPattern(sub); err != nil {
+					return nil, fmt.Errorf("rbac: role %s grants %s with an invalid subject: %w", role.ID, p.ID, err)
+				}
+			}
+
+			if p.IsWildcard() {
+				prefix := strings.TrimSuffix(p.ID, "*")
+				role.wildcards[prefix] = append(role.wildcards[prefix], p)
+
+				continue
+			}
+
+			role.index[p.ID] = append(role.index[p.ID], p)
+		}
+
+		resolved[id] = role
+
+		return effective, nil
+	}
+
+	for _, role := range roles {
+		if _, err := visit(role.ID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,264 @@
+package rbac
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram kept by Metrics.
+var LatencyBuckets = []time.Duration{
+	time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// Metrics counts permission checks, and keeps a histogram of their latency, by permission ID and result.
+// Unless the Enforcer's RegistryMode is RegistryOff, checks of unregistered permissions are counted under the ID
+// "__unknown__", so that arbitrary IDs can't create an unbounded number of series.
+//
+// Metrics implements expvar.Var, and http.Handler serving the Prometheus text exposition format.
+// The default Enforcer records to DefaultMetrics, which is published to expvar as "rbac". Every Enforcer created with
+// NewEnforcer has its own Metrics, so that separate policies don't share series, unless WithMetrics is given; see
+// Enforcer.Metrics.
+type Metrics struct {
+	mu    sync.RWMutex
+	perms map[string]*permissionMetrics
+}
+
+// permissionMetrics holds the histograms for one permission, indexed by whether the check was allowed.
+type permissionMetrics [2]histogram
+
+type histogram struct {
+	// buckets holds a count per bucket in LatencyBuckets, with one more for anything slower.
+	buckets []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Int64
+}
+
+var defaultMetrics = NewMetrics()
+
+func init() {
+	expvar.Publish("rbac", defaultMetrics)
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{perms: make(map[string]*permissionMetrics)}
+}
+
+// DefaultMetrics returns the Metrics recorded to by the default Enforcer, and published to expvar.
+func DefaultMetrics() *Metrics {
+	return defaultMetrics
+}
+
+// MetricsHandler returns an http.Handler serving DefaultMetrics in the Prometheus text exposition format.
+func MetricsHandler() http.Handler {
+	return defaultMetrics
+}
+
+// WithMetrics sets the Metrics that the Enforcer records checks to. Passing nil disables metrics.
+func WithMetrics(m *Metrics) Option {
+	return func(e *Enforcer) {
+		e.metrics = m
+	}
+}
+
+// unknownPermission is the ID that checks of unregistered permissions are counted under. It is reserved, and mustn't
+// be used as the ID of a real permission.
+const unknownPermission = "__unknown__"
+
+// Metrics returns the Metrics the Enforcer records checks to, which is nil if metrics are disabled.
+func (e *Enforcer) Metrics() *Metrics {
+	return e.metrics
+}
+
+// observe records a check of the given permission ID to the Enforcer's Metrics, counting it under unknownPermission
+// if it isn't registered and a registry is in use.
+func (e *Enforcer) observe(id string, allowed bool, elapsed time.Duration) {
+	if e.metrics == nil {
+		return
+	}
+
+	if e.RegistryMode() != RegistryOff && !IsRegistered(Permission{ID: id}) {
+		id = unknownPermission
+	}
+
+	e.metrics.observe(id, allowed, elapsed)
+}
+
+// observe records a check of the given permission ID. It is safe to call on a nil Metrics, which records nothing.
+func (m *Metrics) observe(id string, allowed bool, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.RLock()
+	perm, ok := m.perms[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+		if perm, ok = m.perms[id]; !ok {
+			perm = &permissionMetrics{}
+			perm[0].buckets = make([]atomic.Uint64, len(LatencyBuckets)+1)
+			perm[1].buckets = make([]atomic.Uint64, len(LatencyBuckets)+1)
+			m.perms[id] = perm
+		}
+		m.mu.Unlock()
+	}
+
+	h := &perm[0]
+	if allowed {
+		h = &perm[1]
+	}
+
+	bucket := sort.Search(len(LatencyBuckets), func(i int) bool {
+		return elapsed <= LatencyBuckets[i]
+	})
+
+	h.buckets[bucket].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(elapsed))
+}
+
+// metricsSeries is a point-in-time copy of a histogram, with cumulative bucket counts.
+type metricsSeries struct {
+	permission string
+	result     string
+	buckets    []uint64
+	count      uint64
+	sum        time.Duration
+}
+
+// snapshot copies every histogram, sorted by permission ID and then result.
+func (m *Metrics) snapshot() []metricsSeries {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	series := make([]metricsSeries, 0, len(m.perms)*2)
+
+	for id, perm := range m.perms {
+		for i, result := range [...]string{"denied", "allowed"} {
+			h := &perm[i]
+			s := metricsSeries{
+				permission: id,
+				result:     result,
+				buckets:    make([]uint64, len(h.buckets)),
+				count:      h.count.Load(),
+				sum:        time.Duration(h.sum.Load()),
+			}
+
+			var total uint64
+			for b := range h.buckets {
+				total += h.buckets[b].Load()
+				s.buckets[b] = total
+			}
+
+			series = append(series, s)
+		}
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].permission != series[j].permission {
+			return series[i].permission < series[j].permission
+		}
+
+		return series[i].result < series[j].result
+	})
+
+	return series
+}
+
+// String returns the metrics as JSON, implementing expvar.Var.
+func (m *Metrics) String() string {
+	type result struct {
+		Count      uint64            `json:"count"`
+		SumSeconds float64           `json:"sum_seconds"`
+		Buckets    map[string]uint64 `json:"buckets"`
+	}
+
+	out := make(map[string]map[string]result)
+
+	for _, s := range m.snapshot() {
+		r := result{Count: s.count, SumSeconds: s.sum.Seconds(), Buckets: make(map[string]uint64, len(s.buckets))}
+
+		for i, bound := range LatencyBuckets {
+			r.Buckets[formatSeconds(bound)] = s.buckets[i]
+		}
+
+		r.Buckets["+Inf"] = s.buckets[len(LatencyBuckets)]
+
+		if out[s.permission] == nil {
+			out[s.permission] = make(map[string]result)
+		}
+
+		out[s.permission][s.result] = r
+	}
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "{}"
+	}
+
+	return string(b)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	series := m.snapshot()
+
+	var b strings.Builder
+
+	b.WriteString("# HELP rbac_checks_total Permission checks, by permission ID and result.\n")
+	b.WriteString("# TYPE rbac_checks_total counter\n")
+
+	for _, s := range series {
+		fmt.Fprintf(&b, "rbac_checks_total{%s} %d\n", s.labels(), s.count)
+	}
+
+	b.WriteString("# HELP rbac_check_duration_seconds Latency of permission checks, by permission ID and result.\n")
+	b.WriteString("# TYPE rbac_check_duration_seconds histogram\n")
+
+	for _, s := range series {
+		labels := s.labels()
+
+		for i, bound := range LatencyBuckets {
+			fmt.Fprintf(&b, "rbac_check_duration_seconds_bucket{%s,le=%q} %d\n", labels, formatSeconds(bound), s.buckets[i])
+		}
+
+		fmt.Fprintf(&b, "rbac_check_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.buckets[len(LatencyBuckets)])
+		fmt.Fprintf(&b, "rbac_check_duration_seconds_sum{%s} %s\n", labels, formatSeconds(s.sum))
+		fmt.Fprintf(&b, "rbac_check_duration_seconds_count{%s} %d\n", labels, s.count)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func (s metricsSeries) labels() string {
+	return fmt.Sprintf("permission=\"%s\",result=\"%s\"", escapeLabel(s.permission), s.result)
+}
+
+// escapeLabel escapes a label value as required by the Prometheus text format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package rbac
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsUnknownPermissions(t *testing.T) {
+	view := Permission{ID: "metrics_test.view"}
+	RegisterPermissions(view)
+
+	m := NewMetrics()
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{view}}}, WithMetrics(m), WithRegistryMode(RegistryLog))
+	ctx := userContext("u1", "r")
+
+	e.Can(ctx, view)
+
+	for _, id := range []string{"made_up.a", "made_up.b"} {
+		e.Can(ctx, Permission{ID: id})
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, nil)
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`rbac_checks_total{permission="metrics_test.view",result="allowed"} 1`,
+		`rbac_checks_total{permission="__unknown__",result="denied"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics don't contain %s:\n%s", want, body)
+		}
+	}
+
+	if strings.Contains(body, "made_up") {
+		t.Errorf("metrics contain a series for an unregistered permission:\n%s", body)
+	}
+}
+
+func TestEnforcerMetrics(t *testing.T) {
+	view := Permission{ID: "view"}
+	a := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{view}}})
+	b := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{view}}})
+
+	if a.Metrics() == nil || a.Metrics() == b.Metrics() || a.Metrics() == DefaultMetrics() {
+		t.Fatal("Enforcers share Metrics, want each to have its own")
+	}
+
+	if Default().Metrics() != DefaultMetrics() {
+		t.Error("the default Enforcer doesn't record to DefaultMetrics")
+	}
+
+	a.Can(userContext("u1", "r"), view)
+
+	if !strings.Contains(a.Metrics().String(), `"view"`) || strings.Contains(b.Metrics().String(), `"view"`) {
+		t.Errorf("a check on one Enforcer was recorded as %s and %s", a.Metrics(), b.Metrics())
+	}
+
+	if NewEnforcer(nil, WithMetrics(nil)).Metrics() != nil {
+		t.Error("WithMetrics(nil) didn't disable metrics")
+	}
+}
@@ -2,6 +2,7 @@ package rbac
 
 import (
 	"context"
+	"strings"
 
 	"github.com/ameliaikeda/rbac/subject"
 	"github.com/ameliaikeda/rbac/values"
@@ -15,6 +16,16 @@ type Permission struct {
 
 	// Subjects are things this permission can be applied against, such as a database ID, or a special marker.
 	Subjects []string
+
+	// Condition is an expression that must hold for this permission to apply to a subject, when granted to a role.
+	// It can compare attributes of the user and the subject, e.g. resource.team == user.team; see package condition.
+	// Comparing a missing attribute is an error, so the grant doesn't allow the check, and a condition that refers to
+	// the resource never allows a check without subjects.
+	Condition string
+
+	// Deny marks this permission as an explicit deny when it is granted to a role.
+	// Deny grants override any allow, including allows from the user's other roles.
+	Deny bool
 }
 
 // Equals checks two permissions are the same.
@@ -23,6 +34,41 @@ func (p Permission) Equals(cmp Permission) bool {
 	return p.ID == cmp.ID
 }
 
+// IsWildcard checks if the permission is a wildcard grant, such as items.* or *.
+func (p Permission) IsWildcard() bool {
+	return p.ID == "*" || strings.HasSuffix(p.ID, ".*")
+}
+
+// Covers checks if p, granted to a role, covers the permission cmp.
+// Permission IDs can be namespaced with dots, e.g. items.edit, and a grant of items.* covers every permission
+// under that prefix, including nested ones such as items.comments.edit. A grant of * covers every permission.
+func (p Permission) Covers(cmp Permission) bool {
+	if p.Equals(cmp) || p.ID == "*" {
+		return true
+	}
+
+	if strings.HasSuffix(p.ID, ".*") {
+		return strings.HasPrefix(cmp.ID, strings.TrimSuffix(p.ID, "*"))
+	}
+
+	return false
+}
+
+// unscoped checks if a grant applies to every subject: it has no subjects, or lists subject.Wildcard.
+func (p Permission) unscoped() bool {
+	if len(p.Subjects) == 0 {
+		return true
+	}
+
+	for _, rule := range p.Subjects {
+		if rule == subject.Wildcard {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ValidSubjects checks that all given subjects are valid.
 // If you need a logical OR, see AnyValidSubject.
 func (p Permission) ValidSubjects(ctx context.Context, subjects ...any) bool {
@@ -51,20 +97,84 @@ func (p Permission) AnyValidSubject(ctx context.Context, subjects ...any) bool {
 //
 // - subject.Wildcard will allow any subject as if it matched.
 // - subject.Self will use any available auth in the context to validate against a subject (user) ID.
+// - subject.Self on a subject that exposes its owners, such as with an RBACOwnerID method, matches if the user owns it.
+// - markers added with subject.RegisterMarker match any of the subject IDs their resolver returns.
+// - patterns such as projects/{self}/** match path-like subject IDs; see subject.Pattern.
 func (p Permission) ValidSubject(ctx context.Context, check any) bool {
-	for _, expected := range p.Subjects {
-		expected := expected
+	_, ok := p.matchSubject(ctx, check)
+
+	return ok
+}
+
+// matchSubject works like ValidSubject, but also returns the rule on the permission that matched.
+// Rules are returned as written on the permission, so subject.Self is never replaced with the user's ID.
+//
+// The subject is converted at most once, and never panics, even in strict mode: it has already passed
+// subject.Validate, so a subject that can't be converted, such as a document that only exposes its owners, simply
+// doesn't match rules that need its ID.
+func (p Permission) matchSubject(ctx context.Context, check any) (string, bool) {
+	var (
+		id                     string
+		converted, convertible bool
+	)
+
+	convert := func() bool {
+		if !converted {
+			c, err := subject.Convert(check)
+			id, convertible, converted = c, err == nil, true
+		}
+
+		return convertible
+	}
+
+	for _, rule := range p.Subjects {
+		switch {
+		case rule == subject.Wildcard:
+			return rule, true
 
 		// if `subject.Self` is listed on the permission, replace it with an auth user
-		if expected == subject.Self {
-			if str, ok := values.SubjectFromContext(ctx); ok {
-				expected = str
-			} else {
+		case rule == subject.Self:
+			str, ok := values.SubjectFromContext(ctx)
+			if !ok {
 				continue // we do not have an auth user set up; self can never be checked and so is skipped.
 			}
+
+			// subjects with owners, such as a document, match if the user owns them rather than if they are the user.
+			if owned, hasOwners := subject.OwnedBy(check, str); hasOwners {
+				if owned {
+					return rule, true
+				}
+
+				continue
+			}
+
+			if convert() && id == str {
+				return rule, true
+			}
+
+		case subject.IsPattern(rule):
+			if pattern, err := subject.CachedPattern(rule); err == nil && convert() && pattern.Match(id, placeholders(ctx)) {
+				return rule, true
+			}
+
+		case subject.IsMarker(rule):
+			if ids, ok := subject.Resolve(ctx, rule); ok && convert() && contains(ids, id) {
+				return rule, true
+			}
+
+		default:
+			if convert() && id == rule {
+				return rule, true
+			}
 		}
+	}
 
-		if subject.Matches(expected, check) {
+	return "", false
+}
+
+func contains(ids []string, value string) bool {
+	for _, v := range ids {
+		if v == value {
 			return true
 		}
 	}
@@ -72,6 +182,23 @@ func (p Permission) ValidSubject(ctx context.Context, check any) bool {
 	return false
 }
 
+// placeholders resolves placeholders in subject patterns: {self} is the user's subject ID, and anything else is a marker.
+func placeholders(ctx context.Context) func(name string) []string {
+	return func(name string) []string {
+		if name == "self" {
+			if id, ok := values.SubjectFromContext(ctx); ok {
+				return []string{id}
+			}
+
+			return nil
+		}
+
+		ids, _ := subject.Resolve(ctx, subject.Marker(name))
+
+		return ids
+	}
+}
+
 // WithSubjects adds subjects to the current permission.
 // Usage is e.g. permission.Create.WithSubjects([]string{"foo"})
 func (p Permission) WithSubjects(subjects []string) Permission {
@@ -79,3 +206,19 @@ func (p Permission) WithSubjects(subjects []string) Permission {
 
 	return p
 }
+
+// WithDeny marks the current permission as an explicit deny.
+// Usage is e.g. permission.Delete.WithSubjects([]string{"foo"}).WithDeny()
+func (p Permission) WithDeny() Permission {
+	p.Deny = true
+
+	return p
+}
+
+// WithCondition adds a condition to the current permission, which must hold for it to apply.
+// Usage is e.g. permission.Edit.WithCondition("resource.status != 'locked'")
+func (p Permission) WithCondition(expr string) Permission {
+	p.Condition = expr
+
+	return p
+}
@@ -0,0 +1,43 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+// ownedDoc is a subject that only exposes its owner, so it can't be converted to an ID.
+type ownedDoc struct {
+	owner string
+}
+
+func (d ownedDoc) RBACOwnerID() string { return d.owner }
+
+func TestStrictOwnedSubject(t *testing.T) {
+	subject.SetStrict(true)
+	t.Cleanup(func() { subject.SetStrict(false) })
+
+	edit := Permission{ID: "edit_item"}
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{
+		edit.WithSubjects([]string{"123"}),
+		edit.WithSubjects([]string{"projects/*"}),
+		edit.WithSubjects([]string{subject.Self}),
+	}}})
+	ctx := userContext("u1", "r")
+
+	if !e.Can(ctx, edit, ownedDoc{owner: "u1"}) {
+		t.Error("Can() = false for an owned document, want true")
+	}
+
+	if e.Can(ctx, edit, ownedDoc{owner: "u2"}) {
+		t.Error("Can() = true for another user's document, want false")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Can() didn't panic for an unsupported subject in strict mode")
+		}
+	}()
+
+	e.Can(ctx, edit, struct{}{})
+}
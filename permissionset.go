@@ -0,0 +1,90 @@
+package rbac
+
+import (
+	"context"
+	"time"
+)
+
+// PermissionSet is a group of permissions that can be checked together with CanAll or CanAny.
+// Generated permission packages expose every permission they declare as AllPermissions.
+type PermissionSet []Permission
+
+// IDs returns the ID of every permission in the set, in order.
+func (s PermissionSet) IDs() []string {
+	ids := make([]string, 0, len(s))
+
+	for _, perm := range s {
+		ids = append(ids, perm.ID)
+	}
+
+	return ids
+}
+
+// Contains checks if a permission with the same ID is in the set.
+func (s PermissionSet) Contains(perm Permission) bool {
+	for _, p := range s {
+		if p.Equals(perm) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAll checks that every permission in the set can be taken, verifying subjects for each via logical AND.
+// An empty set is never allowed.
+//
+// Usage: if rbac.CanAll(ctx, rbac.PermissionSet{permissions.ItemCreate, permissions.ItemPublish}) {}
+func CanAll(ctx context.Context, perms PermissionSet, subjects ...any) bool {
+	return defaultEnforcer.CanAll(ctx, perms, subjects...)
+}
+
+// CanAny checks that at least one permission in the set can be taken, verifying subjects for each via logical AND.
+//
+// Usage: if rbac.CanAny(ctx, rbac.PermissionSet{permissions.ItemEdit, permissions.ItemOverride}, item) {}
+func CanAny(ctx context.Context, perms PermissionSet, subjects ...any) bool {
+	return defaultEnforcer.CanAny(ctx, perms, subjects...)
+}
+
+// CanAll checks that every permission in the set can be taken, resolving the user's roles only once.
+func (e *Enforcer) CanAll(ctx context.Context, perms PermissionSet, subjects ...any) bool {
+	return e.checkSet(ctx, true, perms, subjects...)
+}
+
+// CanAny checks that at least one permission in the set can be taken, resolving the user's roles only once.
+func (e *Enforcer) CanAny(ctx context.Context, perms PermissionSet, subjects ...any) bool {
+	return e.checkSet(ctx, false, perms, subjects...)
+}
+
+// checkSet evaluates a PermissionSet using logical AND if all is set, or logical OR otherwise.
+// It logs a single line for the result.
+func (e *Enforcer) checkSet(ctx context.Context, all bool, perms PermissionSet, subjects ...any) bool {
+	base, roles := e.resolve(ctx, e.state.load(), subjects)
+
+	result := all && len(perms) > 0
+	last := base
+
+	for _, perm := range perms {
+		start := time.Now()
+
+		last = e.decide(ctx, base, roles, perm, subjects...)
+		e.observe(last.Permission, last.Allowed, time.Since(start))
+		e.record(last)
+
+		// stop at the first permission that decides the result.
+		if last.Allowed != all {
+			result = last.Allowed
+
+			break
+		}
+	}
+
+	log(ctx, "checking permission set",
+		"rbac.permission.ids", perms.IDs(),
+		"rbac.all", all,
+		"rbac.subject.id", base.SubjectID,
+		"rbac.reason", last.Reason,
+		"rbac.result", result)
+
+	return result
+}
@@ -0,0 +1,325 @@
+package rbac
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ameliaikeda/rbac/generator/mapping"
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+// Policy is a set of roles loaded at runtime, using the same schema as rbac.yaml for cmd/rbac.
+// Options that only apply to code generation, such as go-name and config, are ignored.
+type Policy struct {
+	Roles       map[string]PolicyRole       `yaml:"roles"`
+	Permissions map[string]PolicyPermission `yaml:"permissions"`
+
+	// Markers lists the names of subject markers registered with subject.RegisterMarker, such as team.
+	Markers []string `yaml:"markers"`
+}
+
+// PolicyRole is a role in a Policy, keyed by name. If ID is blank, the key is used.
+type PolicyRole struct {
+	ID          string  `yaml:"id"`
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description"`
+	Permissions []Grant `yaml:"permissions"`
+
+	// Inherits lists the keys of other roles whose permissions this role is also granted.
+	Inherits []string `yaml:"inherits"`
+
+	// ActiveDirectory is stored in the role's CustomMappings, as it is for generated roles.
+	ActiveDirectory string `yaml:"ad-mapping"`
+}
+
+// PolicyPermission is a permission in a Policy, keyed by name. If ID is blank, the key is used.
+type PolicyPermission struct {
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// Grant is a single entry in a role's permissions.
+// It is either a plain string, such as edit_item:self, or a map of one permission to a condition, such as
+// edit_item: "resource.team == user.team".
+type Grant struct {
+	Permission string
+	Condition  string
+}
+
+// UnmarshalYAML decodes a Grant from either of its forms.
+func (g *Grant) UnmarshalYAML(node *yaml.Node) error {
+	if err := checkTag(node); err != nil {
+		return err
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&g.Permission)
+	}
+
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 {
+		if err := checkTag(node.Content[0]); err != nil {
+			return err
+		}
+	}
+
+	var conditional map[string]string
+	if err := node.Decode(&conditional); err != nil {
+		return err
+	}
+
+	if len(conditional) != 1 {
+		return fmt.Errorf("rbac: line %d: a conditional grant must have exactly one permission", node.Line)
+	}
+
+	for perm, cond := range conditional {
+		g.Permission, g.Condition = perm, cond
+	}
+
+	return nil
+}
+
+// checkTag rejects a grant written as an unquoted deny, such as - !delete_item, which YAML reads as a custom tag
+// rather than as part of the value.
+func checkTag(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode || node.Tag == "" || strings.HasPrefix(node.Tag, "!!") {
+		return nil
+	}
+
+	return fmt.Errorf("rbac: line %d: %s%s is read as a YAML tag; quote it, e.g. \"%s%s\"",
+		node.Line, node.Tag, node.Value, node.Tag, node.Value)
+}
+
+// LoadPolicy decodes a Policy from YAML or JSON, and returns its roles, sorted by ID and ready to register with
+// ReplaceRoles. It returns an error if the policy is malformed, or its roles are invalid; see ValidateRoles.
+//
+// Usage: roles, err := rbac.LoadPolicy(f)
+func LoadPolicy(r io.Reader) ([]Role, error) {
+	var policy Policy
+
+	if err := yaml.NewDecoder(r).Decode(&policy); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("rbac: invalid policy: %w", err)
+	}
+
+	return policy.Resolve()
+}
+
+// Resolve converts the policy to roles, sorted by ID, and validates them.
+func (p Policy) Resolve() ([]Role, error) {
+	permissions := make(map[string]Permission, len(p.Permissions))
+	for key, perm := range p.Permissions {
+		if perm.ID == "" {
+			perm.ID = key
+		}
+
+		permissions[key] = Permission{ID: perm.ID, Name: perm.Name, Description: perm.Description}
+	}
+
+	roleIDs := RoleIDs(p.Roles, func(role PolicyRole) string { return role.ID })
+
+	markers := make(map[string]bool, len(p.Markers))
+	for _, name := range p.Markers {
+		markers[name] = true
+	}
+
+	roles := make([]Role, 0, len(p.Roles))
+	for key, role := range p.Roles {
+		r := Role{
+			ID:          roleIDs[key],
+			Name:        role.Name,
+			Description: role.Description,
+			Inherits:    InheritedIDs(role.Inherits, roleIDs),
+			Permissions: make([]Permission, 0, len(role.Permissions)),
+			CustomMappings: map[string]string{
+				mapping.ActiveDirectoryGroupName: role.ActiveDirectory,
+			},
+		}
+
+		for _, grant := range role.Permissions {
+			perm, err := ParseGrant(grant.Permission, markers)
+			if err != nil {
+				return nil, fmt.Errorf("rbac: role %s: %w", r.ID, err)
+			}
+
+			perm.Condition = grant.Condition
+
+			if err := CheckGrant(r.ID, perm, permissions); err != nil {
+				return nil, err
+			}
+
+			if declared, ok := permissions[perm.ID]; ok {
+				perm.ID, perm.Name, perm.Description = declared.ID, declared.Name, declared.Description
+			}
+
+			r.Permissions = append(r.Permissions, perm)
+		}
+
+		roles = append(roles, r)
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].ID < roles[j].ID
+	})
+
+	if err := ValidateRoles(roles); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// CheckGrant checks that a role's grant, as parsed by ParseGrant, names a declared permission, or is a wildcard grant
+// such as items.* that covers at least one. declared is keyed by the name used in grants, as in rbac.yaml.
+func CheckGrant(roleID string, grant Permission, declared map[string]Permission) error {
+	if _, ok := declared[grant.ID]; ok {
+		return nil
+	}
+
+	// wildcard grants such as items.* aren't declared as permissions, but must cover at least one that is.
+	if !grant.IsWildcard() {
+		return fmt.Errorf("rbac: role %s grants %s, which is not a declared permission", roleID, grant.ID)
+	}
+
+	if !coversAny(grant, declared) {
+		return fmt.Errorf("rbac: role %s grants %s, which matches no declared permissions", roleID, grant.ID)
+	}
+
+	return nil
+}
+
+func coversAny(wildcard Permission, permissions map[string]Permission) bool {
+	for _, p := range permissions {
+		if wildcard.Covers(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleIDs maps the key of each role in a policy to the role's ID, for resolving the keys listed in inherits. id returns
+// a role's own ID; roles without one use their key.
+func RoleIDs[R any](roles map[string]R, id func(role R) string) map[string]string {
+	ids := make(map[string]string, len(roles))
+
+	for key, role := range roles {
+		ids[key] = key
+
+		if own := id(role); own != "" {
+			ids[key] = own
+		}
+	}
+
+	return ids
+}
+
+// InheritedIDs converts the keys a role inherits from into role IDs, using the map returned by RoleIDs.
+// Keys that aren't in the map are kept as they are, so they are reported as missing roles by ValidateRoles.
+func InheritedIDs(keys []string, roleIDs map[string]string) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		if id, ok := roleIDs[key]; ok {
+			key = id
+		}
+
+		ids = append(ids, key)
+	}
+
+	return ids
+}
+
+// ParseGrant parses a role's permission, in the syntax used by rbac.yaml, into a Permission with its ID, subjects,
+// and whether it is a deny grant. Deny grants are written as either !key or key:deny, and both forms can be
+// followed by subjects, e.g. !delete:123 or delete:deny:123.
+//
+// Subjects that name a declared or registered marker, such as team, are stored as markers; any other bare subject is
+// a literal ID. Markers can also be written in full, e.g. rbac.team, which is rejected if the marker is neither
+// declared nor registered with subject.RegisterMarker, so a misspelt marker can't silently become an ID.
+func ParseGrant(grant string, markers map[string]bool) (Permission, error) {
+	perm := Permission{}
+
+	if strings.HasPrefix(grant, "!") {
+		perm.Deny = true
+		grant = strings.TrimPrefix(grant, "!")
+	}
+
+	id, subjects, ok := strings.Cut(grant, ":")
+	perm.ID = id
+
+	if id == "" {
+		return Permission{}, fmt.Errorf("grant %q has no permission", grant)
+	}
+
+	if !ok {
+		return perm, nil
+	}
+
+	// we are dealing with a subject permission, e.g. - edit:self, create:*
+	if subjects == "deny" {
+		perm.Deny = true
+
+		return perm, nil
+	}
+
+	if strings.HasPrefix(subjects, "deny:") {
+		perm.Deny = true
+		subjects = strings.TrimPrefix(subjects, "deny:")
+	}
+
+	perm.Subjects = make([]string, 0)
+
+	for _, sub := range strings.Split(subjects, ",") {
+		switch {
+		case sub == "":
+			return Permission{}, fmt.Errorf("grant %q has an empty subject", grant)
+		case sub == subject.Wildcard || sub == "any":
+			perm.Subjects = append(perm.Subjects, subject.Wildcard)
+		case sub == subject.Self || sub == "self":
+			perm.Subjects = append(perm.Subjects, subject.Self)
+		case subject.IsPattern(sub):
+			if err := validatePattern(sub, markers); err != nil {
+				return Permission{}, err
+			}
+
+			perm.Subjects = append(perm.Subjects, sub)
+		case markers[sub] || subject.Registered(sub):
+			perm.Subjects = append(perm.Subjects, subject.Marker(sub))
+		case subject.IsMarker(sub):
+			marker := strings.TrimPrefix(sub, subject.Marker(""))
+			if !markers[marker] && !subject.Registered(marker) {
+				return Permission{}, fmt.Errorf("unknown subject marker %s in %s", marker, grant)
+			}
+
+			perm.Subjects = append(perm.Subjects, sub)
+		default:
+			perm.Subjects = append(perm.Subjects, sub)
+		}
+	}
+
+	return perm, nil
+}
+
+// validatePattern checks a subject pattern's syntax, and that its placeholders are self or known markers.
+func validatePattern(pattern string, markers map[string]bool) error {
+	compiled, err := subject.CompilePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range compiled.Placeholders() {
+		if name != "self" && !markers[name] && !subject.Registered(name) {
+			return fmt.Errorf("unknown placeholder {%s} in subject pattern %s", name, pattern)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+func TestLoadPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{
+			name:   "unquoted deny",
+			policy: "permissions: {delete_item: {}}\nroles:\n  r:\n    permissions:\n      - !delete_item\n",
+			want:   "quote it",
+		},
+		{
+			name:   "empty grant",
+			policy: "permissions: {delete_item: {}}\nroles:\n  r:\n    permissions:\n      - \":self\"\n",
+			want:   "has no permission",
+		},
+		{
+			name:   "empty subject",
+			policy: "permissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - \"edit_item:\"\n",
+			want:   "has an empty subject",
+		},
+		{
+			name:   "empty deny subject",
+			policy: "permissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - edit_item:deny:123,\n",
+			want:   "has an empty subject",
+		},
+		{
+			name:   "undeclared deny",
+			policy: "permissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - \"!delete_item\"\n",
+			want:   "not a declared permission",
+		},
+		{
+			name:   "wildcard without matches",
+			policy: "permissions: {edit_item: {}}\nroles:\n  r:\n    permissions:\n      - items.*\n",
+			want:   "matches no declared permissions",
+		},
+		{
+			name:   "inheritance cycle",
+			policy: "roles:\n  a: {inherits: [b]}\n  b: {inherits: [a]}\n",
+			want:   "cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPolicy(strings.NewReader(tt.policy))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadPolicy() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyDeny(t *testing.T) {
+	roles, err := LoadPolicy(strings.NewReader(`
+permissions:
+  delete_item: {}
+roles:
+  contractor:
+    permissions:
+      - "!delete_item"
+      - delete_item:deny:123
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grants := roles[0].Permissions
+	if len(grants) != 2 || !grants[0].Deny || !grants[1].Deny || len(grants[1].Subjects) != 1 {
+		t.Errorf("LoadPolicy() grants = %+v, want two deny grants", grants)
+	}
+}
+
+// registerTestMarker guards registering the test_region marker, which panics if it happens twice, e.g. with -count=2.
+var registerTestMarker sync.Once
+
+func TestParseGrantMarkers(t *testing.T) {
+	registerTestMarker.Do(func() {
+		subject.RegisterMarker("test_region", func(context.Context) []string { return nil })
+	})
+
+	tests := []struct {
+		grant   string
+		markers map[string]bool
+		want    []string
+		err     string
+	}{
+		{grant: "edit_item:team", markers: map[string]bool{"team": true}, want: []string{"rbac.team"}},
+		{grant: "edit_item:test_region", want: []string{"rbac.test_region"}},
+		{grant: "edit_item:rbac.test_region", want: []string{"rbac.test_region"}},
+		{grant: "edit_item:123,abc-1", want: []string{"123", "abc-1"}},
+		{grant: "edit_item:self,any", want: []string{subject.Self, subject.Wildcard}},
+		{grant: "view_report:public", want: []string{"public"}},
+		{grant: "edit_item:teem", markers: map[string]bool{"team": true}, want: []string{"teem"}},
+		{grant: "edit_item:rbac.teem", err: "unknown subject marker teem"},
+		{grant: "edit_item:teams/{teem}/*", err: "unknown placeholder {teem}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.grant, func(t *testing.T) {
+			perm, err := ParseGrant(tt.grant, tt.markers)
+
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ParseGrant() error = %v, want one containing %q", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseGrant() error = %v", err)
+			}
+
+			if strings.Join(perm.Subjects, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseGrant() subjects = %v, want %v", perm.Subjects, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckGrant(t *testing.T) {
+	declared := map[string]Permission{
+		"edit_item": {ID: "items.edit"},
+	}
+
+	tests := []struct {
+		grant string
+		want  string
+	}{
+		{grant: "edit_item:self"},
+		{grant: "!edit_item"},
+		{grant: "items.*"},
+		{grant: "delete_item", want: "not a declared permission"},
+		{grant: "users.*", want: "matches no declared permissions"},
+	}
+
+	for _, tt := range tests {
+		grant, err := ParseGrant(tt.grant, nil)
+		if err != nil {
+			t.Fatalf("ParseGrant(%q) error = %v", tt.grant, err)
+		}
+
+		err = CheckGrant("r", grant, declared)
+		if tt.want == "" && err != nil {
+			t.Errorf("CheckGrant(%q) error = %v, want nil", tt.grant, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("CheckGrant(%q) error = %v, want one containing %q", tt.grant, err, tt.want)
+		}
+	}
+}
+
+func TestRoleIDs(t *testing.T) {
+	roles := map[string]PolicyRole{
+		"admin":  {ID: "FFFFFFFF-0000"},
+		"viewer": {},
+	}
+
+	ids := RoleIDs(roles, func(role PolicyRole) string { return role.ID })
+
+	if ids["admin"] != "FFFFFFFF-0000" || ids["viewer"] != "viewer" {
+		t.Errorf("RoleIDs() = %v, want admin's own ID and viewer's key", ids)
+	}
+
+	if got := strings.Join(InheritedIDs([]string{"admin", "viewer", "missing"}, ids), ","); got != "FFFFFFFF-0000,viewer,missing" {
+		t.Errorf("InheritedIDs() = %s, want FFFFFFFF-0000,viewer,missing", got)
+	}
+}
@@ -4,11 +4,15 @@
 package rbac
 
 // SetDefaultRoles is something that should ideally be called from an init function.
-// While it is concurrency-safe for read and write access, it's not advisable to change state between requests.
+// Calling it again replaces every role; see ReplaceRoles to reload roles at runtime without panicking.
+//
+// Roles are registered with the default Enforcer; use NewEnforcer to hold roles separately.
 func SetDefaultRoles(roles []Role) {
-	if state == nil {
-		panic("rbac: can't set internal permissions; state is nil")
-	}
+	defaultEnforcer.SetRoles(roles)
+}
 
-	state.setRoles(roles)
+// ReplaceRoles atomically swaps the roles registered with the default Enforcer.
+// If roles are invalid, an error is returned and the current roles are kept.
+func ReplaceRoles(roles []Role) error {
+	return defaultEnforcer.ReplaceRoles(roles)
 }
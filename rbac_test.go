@@ -0,0 +1,81 @@
+package rbac
+
+import (
+	"testing"
+)
+
+func TestSetDefaultRolesTwice(t *testing.T) {
+	view, edit := Permission{ID: "view"}, Permission{ID: "edit"}
+	t.Cleanup(func() { SetDefaultRoles(nil) })
+
+	SetDefaultRoles([]Role{{ID: "a", Permissions: []Permission{view}}, {ID: "b"}})
+	SetDefaultRoles([]Role{{ID: "a", Permissions: []Permission{edit}}, {ID: "c"}})
+
+	ctx := userContext("u1", "a", "b")
+
+	if Can(ctx, view) || !Can(ctx, edit) {
+		t.Errorf("Can() = %v, %v for view and edit, want only the replacement's edit", Can(ctx, view), Can(ctx, edit))
+	}
+
+	if _, ok := RoleByID("b"); ok {
+		t.Error("RoleByID(b) found a role that was replaced")
+	}
+
+	if got := len(Roles(ctx)); got != 1 {
+		t.Errorf("Roles() returned %d roles, want 1", got)
+	}
+}
+
+func TestReplaceRoles(t *testing.T) {
+	view, edit := Permission{ID: "view"}, Permission{ID: "edit"}
+	t.Cleanup(func() { SetDefaultRoles(nil) })
+
+	ctx := userContext("u1", "a")
+
+	for _, perm := range []Permission{view, edit} {
+		if err := ReplaceRoles([]Role{{ID: "a", Permissions: []Permission{perm}}}); err != nil {
+			t.Fatalf("ReplaceRoles() error = %v", err)
+		}
+
+		if !Can(ctx, perm) {
+			t.Errorf("Can(%s) = false after ReplaceRoles(), want true", perm.ID)
+		}
+	}
+
+	if Can(ctx, view) {
+		t.Error("Can(view) = true, want the first roles dropped")
+	}
+
+	invalid := map[string][]Role{
+		"duplicate IDs":   {{ID: "x"}, {ID: "x"}},
+		"missing parent":  {{ID: "x", Inherits: []string{"missing"}}},
+		"inherited cycle": {{ID: "x", Inherits: []string{"y"}}, {ID: "y", Inherits: []string{"x"}}},
+		"bad condition":   {{ID: "x", Permissions: []Permission{view.WithCondition("resource.status ==")}}},
+	}
+
+	for name, roles := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := ReplaceRoles(roles); err == nil {
+				t.Fatal("ReplaceRoles() error = nil")
+			}
+
+			if !Can(ctx, edit) {
+				t.Error("Can() = false, want the current roles kept")
+			}
+		})
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("SetDefaultRoles() didn't panic for invalid roles")
+			}
+		}()
+
+		SetDefaultRoles(invalid["duplicate IDs"])
+	}()
+
+	if !Can(ctx, edit) {
+		t.Error("Can() = false after SetDefaultRoles() panicked, want the current roles kept")
+	}
+}
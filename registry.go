@@ -0,0 +1,140 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RegistryMode controls how an Enforcer treats checks of permissions that weren't added with RegisterPermissions.
+type RegistryMode int32
+
+const (
+	// RegistryOff checks unregistered permissions like any other. It is the default.
+	RegistryOff RegistryMode = iota
+
+	// RegistryLog checks unregistered permissions like any other, but logs each check of one.
+	RegistryLog
+
+	// RegistryEnforce denies checks of unregistered permissions with ReasonUnknownPermission, and rejects roles with
+	// grants for unregistered permissions; see ValidatePermissions.
+	RegistryEnforce
+)
+
+// registered holds every permission added with RegisterPermissions, keyed by ID.
+var registered = struct {
+	mu  sync.RWMutex
+	ids map[string]Permission
+}{ids: make(map[string]Permission)}
+
+// RegisterPermissions adds permissions to the registry used by RegistryLog and RegistryEnforce.
+// Generated permission packages register all of their permissions when they are imported.
+func RegisterPermissions(perms ...Permission) {
+	registered.mu.Lock()
+	defer registered.mu.Unlock()
+
+	for _, perm := range perms {
+		registered.ids[perm.ID] = perm
+	}
+}
+
+// RegisteredPermissions returns every permission added with RegisterPermissions, sorted by ID.
+func RegisteredPermissions() PermissionSet {
+	registered.mu.RLock()
+	defer registered.mu.RUnlock()
+
+	set := make(PermissionSet, 0, len(registered.ids))
+	for _, perm := range registered.ids {
+		set = append(set, perm)
+	}
+
+	sort.Slice(set, func(i, j int) bool {
+		return set[i].ID < set[j].ID
+	})
+
+	return set
+}
+
+// IsRegistered checks if a permission with the same ID was added with RegisterPermissions.
+func IsRegistered(perm Permission) bool {
+	registered.mu.RLock()
+	_, ok := registered.ids[perm.ID]
+	registered.mu.RUnlock()
+
+	return ok
+}
+
+// WithRegistryMode sets how the Enforcer treats checks of unregistered permissions.
+func WithRegistryMode(mode RegistryMode) Option {
+	return func(e *Enforcer) {
+		e.SetRegistryMode(mode)
+	}
+}
+
+// SetRegistryMode sets how the default Enforcer treats checks of unregistered permissions.
+func SetRegistryMode(mode RegistryMode) {
+	defaultEnforcer.SetRegistryMode(mode)
+}
+
+// SetRegistryMode sets how the Enforcer treats checks of unregistered permissions.
+// Roles that are already registered aren't validated; see ValidatePermissions.
+func (e *Enforcer) SetRegistryMode(mode RegistryMode) {
+	e.registryMode.Store(int32(mode))
+}
+
+// RegistryMode returns how the Enforcer treats checks of unregistered permissions.
+func (e *Enforcer) RegistryMode() RegistryMode {
+	return RegistryMode(e.registryMode.Load())
+}
+
+// unregistered checks if a check of perm should be denied because it isn't registered, logging it in RegistryLog mode.
+func (e *Enforcer) unregistered(ctx context.Context, perm Permission) bool {
+	mode := e.RegistryMode()
+	if mode == RegistryOff || IsRegistered(perm) {
+		return false
+	}
+
+	if mode == RegistryLog {
+		log(ctx, "checking unregistered permission", "rbac.permission.id", perm.ID)
+
+		return false
+	}
+
+	return true
+}
+
+// ValidatePermissions checks that every grant of every role registered with the default Enforcer refers to a
+// registered permission. It is intended to be called at startup, after every permission package is imported.
+func ValidatePermissions() error {
+	return defaultEnforcer.ValidatePermissions()
+}
+
+// ValidatePermissions checks that every grant of every role registered with the Enforcer refers to a registered
+// permission, and that every wildcard grant, such as items.*, covers at least one.
+func (e *Enforcer) ValidatePermissions() error {
+	return validateGrants(e.state.allRoles())
+}
+
+func validateGrants(roles []Role) error {
+	registered.mu.RLock()
+	defer registered.mu.RUnlock()
+
+	for _, role := range roles {
+		for _, p := range role.Permissions {
+			if _, ok := registered.ids[p.ID]; ok {
+				continue
+			}
+
+			if !p.IsWildcard() {
+				return fmt.Errorf("rbac: role %s grants %s, which is not a registered permission", role.ID, p.ID)
+			}
+
+			if !coversAny(p, registered.ids) {
+				return fmt.Errorf("rbac: role %s grants %s, which matches no registered permissions", role.ID, p.ID)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+// the registry is global, so permissions registered by tests are prefixed with the test file's name.
+var (
+	registryView = Permission{ID: "registry_test.view"}
+	registryEdit = Permission{ID: "registry_test.edit"}
+)
+
+func init() {
+	RegisterPermissions(registryView, registryEdit)
+}
+
+func TestRegistryEnforceChecks(t *testing.T) {
+	unknown := Permission{ID: "registry_test_unknown.view"}
+	roles := []Role{{ID: "r", Permissions: []Permission{registryView, unknown}}}
+	ctx := userContext("u1", "r")
+
+	e := NewEnforcer(roles, WithRegistryMode(RegistryLog))
+
+	if !e.Can(ctx, unknown) {
+		t.Error("Can() = false for an unregistered permission with RegistryLog, want true")
+	}
+
+	e.SetRegistryMode(RegistryEnforce)
+
+	if e.Can(ctx, unknown) {
+		t.Error("Can() = true for an unregistered permission with RegistryEnforce, want false")
+	}
+
+	if got := e.Explain(ctx, unknown).Reason; got != ReasonUnknownPermission {
+		t.Errorf("Explain() reason = %s, want %s", got, ReasonUnknownPermission)
+	}
+
+	if !e.Can(ctx, registryView) {
+		t.Error("Can() = false for a registered permission with RegistryEnforce, want true")
+	}
+
+	// roles registered before the mode changed aren't validated until asked.
+	if err := e.ValidatePermissions(); err == nil || !strings.Contains(err.Error(), "not a registered permission") {
+		t.Errorf("ValidatePermissions() error = %v, want the unregistered grant", err)
+	}
+}
+
+func TestRegistryEnforceReplaceRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		grant Permission
+		want  string
+	}{
+		{"registered", registryEdit, ""},
+		{"registered deny", registryEdit.WithDeny(), ""},
+		{"covering wildcard", Permission{ID: "registry_test.*"}, ""},
+		{"unregistered", Permission{ID: "registry_test.delete"}, "not a registered permission"},
+		{"empty wildcard", Permission{ID: "registry_test_unknown.*"}, "matches no registered permissions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnforcer([]Role{{ID: "current", Permissions: []Permission{registryView}}}, WithRegistryMode(RegistryEnforce))
+
+			err := e.ReplaceRoles([]Role{{ID: "r", Permissions: []Permission{tt.grant}}})
+
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ReplaceRoles() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReplaceRoles() error = %v, want one containing %q", err, tt.want)
+			}
+
+			if _, ok := e.RoleByID("current"); !ok {
+				t.Error("ReplaceRoles() replaced the roles despite an error")
+			}
+		})
+	}
+}
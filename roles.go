@@ -2,6 +2,8 @@ package rbac
 
 import (
 	"context"
+
+	"github.com/ameliaikeda/rbac/subject"
 )
 
 // Role is a base unit that can be assigned a group or a user, and contains a set of permissions.
@@ -20,26 +22,237 @@ type Role struct {
 	Description string
 	Permissions []Permission
 
+	// Inherits holds the IDs of roles whose permissions are also granted by this role.
+	// Inherited permissions are resolved when roles are registered with SetDefaultRoles.
+	Inherits []string
+
 	// CustomMappings holds any extra data at generation time used to map this role to another system.
 	CustomMappings map[string]string
+
+	// effective holds Permissions plus every inherited permission, and is set when the role is registered.
+	effective []Permission
+
+	// index holds effective grouped by permission ID, so registered roles don't scan every grant on each check.
+	index map[string][]Permission
+
+	// wildcards holds wildcard grants from effective, keyed by prefix, e.g. "items." for items.* and "" for *.
+	wildcards map[string][]Permission
 }
 
 // Can checks if a role has a specific permission. If a subject is passed, they are verified via logical AND.
+//
+// Subjects are checked against the role's own grant for the permission, not the Permission passed in,
+// so a grant of edit_item:self only allows the current user. A grant without any subjects is unscoped and
+// allows every subject. If the role holds several grants for the same ID, each subject may be allowed by any of them.
+// A check without subjects is only allowed by a grant that applies to every subject: one without subjects, or one
+// listing subject.Wildcard. Grants scoped to subject.Self or to IDs never allow it.
+//
+// Grants with a Condition only allow subjects for which the condition holds; see Permission.Condition.
+// Deny grants on the role take precedence over any allow; see Permission.Deny.
+// Inherited permissions are only checked on roles that have been registered, e.g. those returned by Roles or RoleByID.
+// A role value that wasn't registered, such as a generated roles.Admin, only has its own permissions; use
+// Enforcer.RoleByID to check it with the permissions it inherits from an Enforcer's roles.
 func (r Role) Can(ctx context.Context, perm Permission, subjects ...any) bool {
-	if !r.Has(perm) {
+	if _, denied := r.denies(ctx, perm, subjects...); denied {
 		return false
 	}
 
-	return perm.ValidSubjects(ctx, subjects...)
+	return r.allows(ctx, perm, subjects...)
+}
+
+// allows works like Can, but ignores deny grants.
+func (r Role) allows(ctx context.Context, perm Permission, subjects ...any) bool {
+	if len(subjects) == 0 {
+		_, ok := r.unscopedGrant(ctx, perm)
+
+		return ok
+	}
+
+	for _, sub := range subjects {
+		if _, _, ok := r.matchSubject(ctx, perm, sub); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// explain works like Can, but returns the grant and subject rules that allowed the check, or the reason it was denied.
+// The grant returned is the one that allowed the first subject, and rules are returned in the same order as subjects.
+func (r Role) explain(ctx context.Context, perm Permission, subjects ...any) (Permission, []string, Reason) {
+	if !r.Has(perm) {
+		return Permission{}, nil, ReasonNotGranted
+	}
+
+	if len(subjects) == 0 {
+		grant, ok := r.unscopedGrant(ctx, perm)
+		if !ok {
+			if r.hasUnscopedRule(perm) {
+				return Permission{}, nil, ReasonConditionFailed
+			}
+
+			return Permission{}, nil, ReasonSubjectMismatch
+		}
+
+		return grant, nil, ReasonGranted
+	}
+
+	var grant Permission
+
+	rules := make([]string, 0, len(subjects))
+
+	for i, sub := range subjects {
+		matched, rule, ok := r.matchSubject(ctx, perm, sub)
+		if !ok {
+			if r.matchesRule(ctx, perm, sub) {
+				return Permission{}, nil, ReasonConditionFailed
+			}
+
+			return Permission{}, nil, ReasonSubjectMismatch
+		}
+
+		if i == 0 {
+			grant = matched
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return grant, rules, ReasonGranted
+}
+
+// denies returns the first deny grant on the role that applies to perm and subjects.
+// A deny grant without subjects, or listing subject.Wildcard, denies every check; otherwise it denies checks where any
+// subject matches it.
+// Deny grants with a condition only apply when it holds, or when it can't be evaluated.
+func (r Role) denies(ctx context.Context, perm Permission, subjects ...any) (grant Permission, denied bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		if p.Deny && p.deniesSubjects(ctx, subjects...) {
+			grant, denied = p, true
+		}
+
+		return !denied
+	})
+
+	return grant, denied
+}
+
+// grant returns the first allow grant on the role that covers perm.
+func (r Role) grant(perm Permission) (grant Permission, ok bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		if !p.Deny {
+			grant, ok = p, true
+		}
+
+		return !ok
+	})
+
+	return grant, ok
+}
+
+// unscopedGrant returns the first allow grant on the role that covers perm for every subject, and whose condition
+// holds without a subject. It is used for checks without subjects, so conditions on the resource never hold.
+func (r Role) unscopedGrant(ctx context.Context, perm Permission) (grant Permission, ok bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		if !p.Deny && p.unscoped() && !p.needsResource() && p.conditionHolds(ctx, nil, false) {
+			grant, ok = p, true
+		}
+
+		return !ok
+	})
+
+	return grant, ok
+}
+
+// hasUnscopedRule works like unscopedGrant, but ignores conditions. It is used to explain why a check was denied.
+func (r Role) hasUnscopedRule(perm Permission) (ok bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		ok = !p.Deny && p.unscoped()
+
+		return !ok
+	})
+
+	return ok
+}
+
+// matchSubject checks a single subject against every grant on the role that covers perm.
+// It returns the grant and rule that allowed it; unscoped grants are reported as subject.Wildcard.
+func (r Role) matchSubject(ctx context.Context, perm Permission, sub any) (grant Permission, rule string, ok bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		switch {
+		case p.Deny:
+			return true
+
+		case len(p.Subjects) == 0:
+			if p.conditionHolds(ctx, sub, false) {
+				grant, rule, ok = p, subject.Wildcard, true
+			}
+
+		default:
+			if matched, valid := p.matchSubject(ctx, sub); valid && p.conditionHolds(ctx, sub, false) {
+				grant, rule, ok = p, matched, true
+			}
+		}
+
+		return !ok
+	})
+
+	return grant, rule, ok
+}
+
+// matchesRule works like matchSubject, but ignores conditions. It is used to explain why a subject didn't match.
+func (r Role) matchesRule(ctx context.Context, perm Permission, sub any) (ok bool) {
+	r.eachGrant(perm, func(p Permission) bool {
+		ok = !p.Deny && (len(p.Subjects) == 0 || p.ValidSubject(ctx, sub))
+
+		return !ok
+	})
+
+	return ok
 }
 
 // Has checks if a role has a specific permission, regardless of subjects.
+// Wildcard grants such as items.* are taken into account; see Permission.Covers.
+// Deny grants are not considered, so Has can be true even when Can is not.
 func (r Role) Has(perm Permission) bool {
-	for _, p := range r.Permissions {
-		if perm.Equals(p) {
-			return true
+	_, ok := r.grant(perm)
+
+	return ok
+}
+
+// eachGrant calls fn with each of the role's effective grants that cover perm, until fn returns false.
+// Roles that were never registered have no index, so every permission on the role is checked instead.
+func (r Role) eachGrant(perm Permission, fn func(Permission) bool) {
+	if r.index == nil {
+		for _, p := range r.Permissions {
+			if p.Covers(perm) && !fn(p) {
+				return
+			}
 		}
+
+		return
+	}
+
+	for _, p := range r.index[perm.ID] {
+		if !fn(p) {
+			return
+		}
+	}
+
+	if len(r.wildcards) == 0 {
+		return
 	}
 
-	return false
+	// look up wildcard grants for every prefix of the ID, e.g. "", "items." and "items.comments." for items.comments.edit.
+	for i := 0; i <= len(perm.ID); i++ {
+		if i > 0 && perm.ID[i-1] != '.' {
+			continue
+		}
+
+		for _, p := range r.wildcards[perm.ID[:i]] {
+			if !fn(p) {
+				return
+			}
+		}
+	}
 }
@@ -0,0 +1,126 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+	"github.com/ameliaikeda/rbac/values"
+)
+
+// testUser is a values.User for tests.
+type testUser struct {
+	id    string
+	roles []string
+	attrs map[string]any
+}
+
+func (u testUser) RBACSubjectID() string          { return u.id }
+func (u testUser) RBACRoles() []string            { return u.roles }
+func (u testUser) RBACAttributes() map[string]any { return u.attrs }
+
+func userContext(id string, roles ...string) context.Context {
+	return values.Embed(context.Background(), testUser{id: id, roles: roles})
+}
+
+func TestRoleCanSubjects(t *testing.T) {
+	edit := Permission{ID: "edit_item"}
+
+	tests := []struct {
+		name     string
+		grants   []Permission
+		subjects []any
+		want     bool
+	}{
+		{"unscoped without subject", []Permission{edit}, nil, true},
+		{"unscoped with subject", []Permission{edit}, []any{"1"}, true},
+		{"self without subject", []Permission{edit.WithSubjects([]string{subject.Self})}, nil, false},
+		{"id without subject", []Permission{edit.WithSubjects([]string{"123"})}, nil, false},
+		{"any without subject", []Permission{edit.WithSubjects([]string{subject.Wildcard})}, nil, true},
+		{"self with own ID", []Permission{edit.WithSubjects([]string{subject.Self})}, []any{"u1"}, true},
+		{"self with other ID", []Permission{edit.WithSubjects([]string{subject.Self})}, []any{"u2"}, false},
+		{"id with matching ID", []Permission{edit.WithSubjects([]string{"123"})}, []any{123}, true},
+		{"either of two grants", []Permission{edit.WithSubjects([]string{"1"}), edit.WithSubjects([]string{"2"})}, []any{"1", "2"}, true},
+		{"deny overrides allow", []Permission{edit, edit.WithDeny()}, nil, false},
+		{"scoped deny", []Permission{edit, edit.WithSubjects([]string{"1"}).WithDeny()}, []any{"1"}, false},
+		{"scoped deny for another subject", []Permission{edit, edit.WithSubjects([]string{"1"}).WithDeny()}, []any{"2"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := Role{ID: "r", Permissions: tt.grants}
+
+			if got := role.Can(userContext("u1", "r"), edit, tt.subjects...); got != tt.want {
+				t.Errorf("Can() = %v, want %v", got, tt.want)
+			}
+
+			e := NewEnforcer([]Role{role})
+			if got := e.Can(userContext("u1", "r"), edit, tt.subjects...); got != tt.want {
+				t.Errorf("Enforcer.Can() = %v, want %v", got, tt.want)
+			}
+
+			if got := e.Explain(userContext("u1", "r"), edit, tt.subjects...).Allowed; got != tt.want {
+				t.Errorf("Enforcer.Explain().Allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnregisteredRoleInherits(t *testing.T) {
+	view, edit := Permission{ID: "view"}, Permission{ID: "edit"}
+	viewer := Role{ID: "test_viewer", Permissions: []Permission{view}}
+	admin := Role{ID: "test_admin", Inherits: []string{viewer.ID}, Permissions: []Permission{edit}}
+
+	// another product's roles with the same ID don't change an unregistered role value.
+	SetDefaultRoles([]Role{viewer, {ID: admin.ID}})
+	t.Cleanup(func() { SetDefaultRoles(nil) })
+
+	if !admin.Has(edit) || admin.Has(view) {
+		t.Errorf("Has() = %v, %v for edit and view, want only the role's own permissions", admin.Has(edit), admin.Has(view))
+	}
+
+	e := NewEnforcer([]Role{viewer, admin})
+
+	registered, ok := e.RoleByID(admin.ID)
+	if !ok {
+		t.Fatal("RoleByID() found no role")
+	}
+
+	if !registered.Has(view) || !registered.Can(userContext("u1"), view) {
+		t.Error("Has() or Can() = false for a registered role, want true from the inherited role")
+	}
+
+	if _, ok := e.RoleByID("missing"); ok {
+		t.Error("RoleByID() found a role that wasn't registered")
+	}
+}
+
+func TestDenyWildcardWithoutSubjects(t *testing.T) {
+	del := Permission{ID: "delete_item"}
+
+	for _, grant := range []string{"delete_item:deny:*", "delete_item:deny:any", "!delete_item"} {
+		t.Run(grant, func(t *testing.T) {
+			deny, err := ParseGrant(grant, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{del, deny}}})
+
+			if e.Can(userContext("u1", "r"), del) {
+				t.Error("Can() = true without subjects, want false")
+			}
+
+			if e.Can(userContext("u1", "r"), del, "123") {
+				t.Error("Can() = true for a subject, want false")
+			}
+		})
+	}
+
+	// a deny grant scoped to IDs doesn't deny checks without subjects.
+	e := NewEnforcer([]Role{{ID: "r", Permissions: []Permission{del, del.WithDeny().WithSubjects([]string{"123"})}}})
+
+	if !e.Can(userContext("u1", "r"), del) {
+		t.Error("Can() = false without subjects for a deny scoped to an ID, want true")
+	}
+}
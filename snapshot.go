@@ -0,0 +1,184 @@
+package rbac
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SnapshotVersion is the version of the format produced by Snapshot. Restore rejects any other version.
+const SnapshotVersion = 1
+
+// PolicySnapshot is a serializable view of every role registered with an Enforcer.
+//
+// Roles are sorted by ID, so two Enforcers with the same roles produce the same snapshot, and the same Hash.
+type PolicySnapshot struct {
+	Version int `json:"version"`
+
+	// Hash is the sha256 of the snapshot's canonical encoding without the hash itself, e.g. sha256:9f86d0...
+	Hash string `json:"hash,omitempty"`
+
+	Roles []SnapshotRole `json:"roles"`
+}
+
+// SnapshotRole is a role in a PolicySnapshot, holding its own grants rather than those it inherits.
+type SnapshotRole struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Inherits       []string          `json:"inherits,omitempty"`
+	Grants         []SnapshotGrant   `json:"grants"`
+	CustomMappings map[string]string `json:"custom_mappings,omitempty"`
+}
+
+// SnapshotGrant is a single permission granted, or explicitly denied, by a SnapshotRole.
+type SnapshotGrant struct {
+	Permission  string   `json:"permission"`
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Subjects    []string `json:"subjects,omitempty"`
+	Condition   string   `json:"condition,omitempty"`
+	Deny        bool     `json:"deny,omitempty"`
+}
+
+// Snapshot returns a PolicySnapshot of the roles registered with the default Enforcer.
+//
+// Usage: json.NewEncoder(w).Encode(rbac.Snapshot())
+func Snapshot() PolicySnapshot {
+	return defaultEnforcer.Snapshot()
+}
+
+// Restore replaces the roles registered with the default Enforcer with those from a PolicySnapshot.
+func Restore(snapshot PolicySnapshot) error {
+	return defaultEnforcer.Restore(snapshot)
+}
+
+// Snapshot returns a PolicySnapshot of the roles registered with the Enforcer.
+func (e *Enforcer) Snapshot() PolicySnapshot {
+	roles := e.state.allRoles()
+
+	snapshot := PolicySnapshot{
+		Version: SnapshotVersion,
+		Roles:   make([]SnapshotRole, 0, len(roles)),
+	}
+
+	for _, role := range roles {
+		r := SnapshotRole{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Inherits:    append([]string(nil), role.Inherits...),
+			Grants:      make([]SnapshotGrant, 0, len(role.Permissions)),
+		}
+
+		// copy anything mutable, as registered roles must never change.
+		for key, value := range role.CustomMappings {
+			if r.CustomMappings == nil {
+				r.CustomMappings = make(map[string]string, len(role.CustomMappings))
+			}
+
+			r.CustomMappings[key] = value
+		}
+
+		for _, p := range role.Permissions {
+			r.Grants = append(r.Grants, SnapshotGrant{
+				Permission:  p.ID,
+				Name:        p.Name,
+				Description: p.Description,
+				Subjects:    append([]string(nil), p.Subjects...),
+				Condition:   p.Condition,
+				Deny:        p.Deny,
+			})
+		}
+
+		snapshot.Roles = append(snapshot.Roles, r)
+	}
+
+	sort.Slice(snapshot.Roles, func(i, j int) bool {
+		return snapshot.Roles[i].ID < snapshot.Roles[j].ID
+	})
+
+	snapshot.Hash = snapshot.hash()
+
+	return snapshot
+}
+
+// Restore replaces the Enforcer's roles with those from a PolicySnapshot.
+// It returns an error, keeping the current roles, if the snapshot's version is unsupported, its Hash doesn't match
+// its roles, or its roles are invalid. A blank Hash isn't checked, so snapshots can be written by hand.
+func (e *Enforcer) Restore(snapshot PolicySnapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("rbac: unsupported snapshot version %d", snapshot.Version)
+	}
+
+	if snapshot.Hash != "" && snapshot.Hash != snapshot.hash() {
+		return fmt.Errorf("rbac: snapshot hash %s doesn't match its roles", snapshot.Hash)
+	}
+
+	return e.ReplaceRoles(snapshot.toRoles())
+}
+
+// Canonical returns the canonical JSON encoding of the snapshot: compact, with roles sorted by ID and object keys
+// in a fixed order. Snapshots with the same roles always have the same canonical encoding.
+func (s PolicySnapshot) Canonical() []byte {
+	roles := make([]SnapshotRole, 0, len(s.Roles))
+	for _, r := range s.Roles {
+		if r.Grants == nil {
+			r.Grants = []SnapshotGrant{}
+		}
+
+		roles = append(roles, r)
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].ID < roles[j].ID
+	})
+
+	s.Roles = roles
+
+	// a snapshot only holds strings, bools, slices and maps of strings, so it always encodes.
+	b, _ := json.Marshal(s)
+
+	return b
+}
+
+// hash returns the sha256 of the canonical encoding of the snapshot without its Hash.
+func (s PolicySnapshot) hash() string {
+	s.Hash = ""
+
+	sum := sha256.Sum256(s.Canonical())
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (s PolicySnapshot) toRoles() []Role {
+	roles := make([]Role, 0, len(s.Roles))
+
+	for _, r := range s.Roles {
+		role := Role{
+			ID:             r.ID,
+			Name:           r.Name,
+			Description:    r.Description,
+			Inherits:       r.Inherits,
+			Permissions:    make([]Permission, 0, len(r.Grants)),
+			CustomMappings: r.CustomMappings,
+		}
+
+		for _, g := range r.Grants {
+			role.Permissions = append(role.Permissions, Permission{
+				ID:          g.Permission,
+				Name:        g.Name,
+				Description: g.Description,
+				Subjects:    g.Subjects,
+				Condition:   g.Condition,
+				Deny:        g.Deny,
+			})
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles
+}
@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ameliaikeda/rbac/subject"
+)
+
+// snapshotRoles covers everything a snapshot holds: inheritance, subjects, conditions and deny grants.
+func snapshotRoles() []Role {
+	view, edit, del := Permission{ID: "view"}, Permission{ID: "edit"}, Permission{ID: "delete"}
+
+	return []Role{
+		{ID: "viewer", Name: "Viewer", Permissions: []Permission{view}},
+		{ID: "editor", Inherits: []string{"viewer"}, Permissions: []Permission{
+			edit.WithSubjects([]string{subject.Self}).WithCondition("resource.status != 'locked'"),
+			del.WithDeny(),
+		}},
+	}
+}
+
+func TestSnapshotCanonical(t *testing.T) {
+	snapshot := NewEnforcer(snapshotRoles()).Snapshot()
+
+	want := `{"version":1,"roles":[` +
+		`{"id":"editor","inherits":["viewer"],"grants":[` +
+		`{"permission":"edit","subjects":["rbac.self"],"condition":"resource.status != 'locked'"},` +
+		`{"permission":"delete","deny":true}]},` +
+		`{"id":"viewer","name":"Viewer","grants":[{"permission":"view"}]}]}`
+
+	unhashed := snapshot
+	unhashed.Hash = ""
+
+	if got := string(unhashed.Canonical()); got != want {
+		t.Errorf("Canonical() =\n%s\nwant\n%s", got, want)
+	}
+
+	if !strings.HasPrefix(snapshot.Hash, "sha256:") {
+		t.Errorf("Hash = %q, want a sha256", snapshot.Hash)
+	}
+
+	// the same roles in another order have the same hash.
+	roles := snapshotRoles()
+	roles[0], roles[1] = roles[1], roles[0]
+
+	if got := NewEnforcer(roles).Snapshot().Hash; got != snapshot.Hash {
+		t.Errorf("Hash = %s for reordered roles, want %s", got, snapshot.Hash)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	b, err := json.Marshal(NewEnforcer(snapshotRoles()).Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot PolicySnapshot
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEnforcer(nil)
+	if err := e.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if got := e.Snapshot(); string(got.Canonical()) != string(snapshot.Canonical()) {
+		t.Errorf("Snapshot() after Restore() =\n%s\nwant\n%s", got.Canonical(), snapshot.Canonical())
+	}
+
+	ctx := userContext("u1", "editor")
+
+	if !e.Can(ctx, Permission{ID: "view"}) {
+		t.Error("Can(view) = false, want true from the inherited role")
+	}
+
+	if !e.Can(ctx, Permission{ID: "edit"}, attributed{"u1", map[string]any{"status": "open"}}) {
+		t.Error("Can(edit) = false for the user's own open item, want true")
+	}
+
+	if e.Can(ctx, Permission{ID: "edit"}, attributed{"u1", map[string]any{"status": "locked"}}) {
+		t.Error("Can(edit) = true for a locked item, want false")
+	}
+
+	if e.Can(ctx, Permission{ID: "delete"}) {
+		t.Error("Can(delete) = true, want false from the deny grant")
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	valid := NewEnforcer(snapshotRoles()).Snapshot()
+
+	tampered := NewEnforcer(snapshotRoles()).Snapshot()
+	tampered.Roles[0].Grants[1].Deny = false
+
+	version := valid
+	version.Version = SnapshotVersion + 1
+
+	invalid := PolicySnapshot{Version: SnapshotVersion, Roles: []SnapshotRole{{ID: "a", Inherits: []string{"missing"}}}}
+
+	tests := []struct {
+		name     string
+		snapshot PolicySnapshot
+		want     string
+	}{
+		{"hash mismatch", tampered, "doesn't match its roles"},
+		{"version", version, "unsupported snapshot version"},
+		{"invalid roles", invalid, "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnforcer([]Role{{ID: "current"}})
+
+			err := e.Restore(tt.snapshot)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Restore() error = %v, want one containing %q", err, tt.want)
+			}
+
+			if _, ok := e.RoleByID("current"); !ok {
+				t.Error("Restore() replaced the roles despite an error")
+			}
+		})
+	}
+
+	// a blank hash isn't checked, so hand-written snapshots can be restored.
+	valid.Hash = ""
+
+	if err := NewEnforcer(nil).Restore(valid); err != nil {
+		t.Errorf("Restore() error = %v for a snapshot without a hash", err)
+	}
+}
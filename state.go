@@ -1,44 +1,89 @@
 package rbac
 
 import (
-	"fmt"
-	"sync"
+	"context"
+	"sync/atomic"
 )
 
-type internalState struct {
-	sync.RWMutex
+// registry is an immutable snapshot of the roles registered with an Enforcer.
+// It is never modified once stored, so a check that loads it sees a single, consistent policy.
+type registry struct {
 	roles   []Role
 	roleMap map[string]Role
 }
 
-// state should not be manipulated outside of tests, and is not concurrency-safe to change.
-var state = &internalState{
-	roles:   make([]Role, 0),
-	roleMap: make(map[string]Role),
+type internalState struct {
+	current atomic.Pointer[registry]
 }
 
-func (s *internalState) allRoles() []Role {
-	s.RLock()
-	defer s.RUnlock()
+// newState creates an empty registry for an Enforcer.
+func newState() *internalState {
+	s := &internalState{}
+
+	s.current.Store(&registry{
+		roles:   make([]Role, 0),
+		roleMap: make(map[string]Role),
+	})
 
-	return s.roles
+	return s
 }
 
-func (s *internalState) roleByID(id string) Role {
-	s.RLock()
-	defer s.RUnlock()
+func (s *internalState) load() *registry {
+	return s.current.Load()
+}
 
-	return s.roleMap[id]
+func (s *internalState) allRoles() []Role {
+	return s.load().roles
+}
+
+func (s *internalState) roleByID(id string) Role {
+	return s.load().roleMap[id]
 }
 
 func (s *internalState) rolesByID(ids []string) []Role {
-	s.RLock()
-	defer s.RUnlock()
+	return s.load().rolesByID(ids)
+}
+
+func (s *internalState) can(ctx context.Context, ids []string, perm Permission, subjects ...any) bool {
+	return s.load().can(ctx, ids, perm, subjects...)
+}
+
+// setRoles builds a new registry from roles and swaps it in, replacing every previously registered role.
+// If roles are invalid, the current registry is left in place.
+func (s *internalState) setRoles(roles []Role) error {
+	reg, err := newRegistry(roles)
+	if err != nil {
+		return err
+	}
 
+	s.current.Store(reg)
+
+	return nil
+}
+
+func newRegistry(roles []Role) (*registry, error) {
+	resolved, err := resolveRoles(roles)
+	if err != nil {
+		return nil, err
+	}
+
+	reg := &registry{
+		roles:   make([]Role, 0, len(roles)),
+		roleMap: resolved,
+	}
+
+	for _, role := range roles {
+		reg.roles = append(reg.roles, resolved[role.ID])
+	}
+
+	return reg, nil
+}
+
+func (r *registry) rolesByID(ids []string) []Role {
 	roles := make([]Role, 0, len(ids))
 
 	for _, id := range ids {
-		if role, ok := s.roleMap[id]; ok {
+		if role, ok := r.roleMap[id]; ok {
 			roles = append(roles, role)
 		}
 	}
@@ -46,18 +91,24 @@ func (s *internalState) rolesByID(ids []string) []Role {
 	return roles
 }
 
-func (s *internalState) setRoles(roles []Role) {
-	s.Lock()
-	defer s.Unlock()
+// can checks a permission against the registered roles in ids without allocating, applying deny-overrides semantics.
+func (r *registry) can(ctx context.Context, ids []string, perm Permission, subjects ...any) bool {
+	allowed := false
 
-	s.roles = roles
+	for _, id := range ids {
+		role, ok := r.roleMap[id]
+		if !ok {
+			continue
+		}
 
-	for _, role := range s.roles {
-		// don't allow duplicates because of undefined behavior.
-		if _, exists := s.roleMap[role.ID]; exists {
-			panic(fmt.Sprintf("rbac: duplicate permission ID when setting up: %s (%s)", role.ID, role.Name))
+		if _, denied := role.denies(ctx, perm, subjects...); denied {
+			return false
 		}
 
-		s.roleMap[role.ID] = role
+		if !allowed && role.allows(ctx, perm, subjects...) {
+			allowed = true
+		}
 	}
+
+	return allowed
 }
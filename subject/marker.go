@@ -0,0 +1,71 @@
+package subject
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// markerPrefix namespaces markers, so they can't be confused with literal subject IDs; Self is rbac.self.
+const markerPrefix = "rbac."
+
+// Resolver returns the subject IDs a marker allows for the user in the context, e.g. the IDs of the user's team.
+type Resolver func(ctx context.Context) []string
+
+var (
+	markersMu sync.RWMutex
+	markers   = make(map[string]Resolver)
+)
+
+// Marker returns the subject value for a marker name, as stored on a permission, e.g. Marker("team") is rbac.team.
+func Marker(name string) string {
+	return markerPrefix + name
+}
+
+// RegisterMarker adds a marker that can be used in place of a subject ID, such as edit_item:team.
+// It should be called from an init function, and panics if the name is reserved or already registered.
+func RegisterMarker(name string, resolver Resolver) {
+	if name == "" || name == "self" || name == "any" || strings.ContainsAny(name, ":,*") {
+		panic(fmt.Sprintf("rbac: marker name is reserved or invalid: %q", name))
+	}
+
+	markersMu.Lock()
+	defer markersMu.Unlock()
+
+	if _, exists := markers[name]; exists {
+		panic(fmt.Sprintf("rbac: marker registered twice: %s", name))
+	}
+
+	markers[name] = resolver
+}
+
+// IsMarker checks if a subject value, as stored on a permission, is a marker.
+// Any value with the marker prefix is treated as a marker, whether or not it has been registered.
+func IsMarker(value string) bool {
+	return strings.HasPrefix(value, markerPrefix)
+}
+
+// Registered checks if a marker name has been registered with RegisterMarker.
+func Registered(name string) bool {
+	markersMu.RLock()
+	defer markersMu.RUnlock()
+
+	_, ok := markers[name]
+
+	return ok
+}
+
+// Resolve returns the subject IDs allowed by a marker value, such as rbac.team, for the user in the context.
+// ok is false if the marker hasn't been registered. Self is resolved by callers, and is never registered.
+func Resolve(ctx context.Context, marker string) (ids []string, ok bool) {
+	markersMu.RLock()
+	resolver, ok := markers[strings.TrimPrefix(marker, markerPrefix)]
+	markersMu.RUnlock()
+
+	if !ok || !IsMarker(marker) {
+		return nil, false
+	}
+
+	return resolver(ctx), true
+}
@@ -0,0 +1,262 @@
+package subject
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Pattern is a compiled subject pattern, for path-like subject IDs such as projects/42/files/7.
+//
+// Patterns are split into segments by "/", and support:
+// - `*`, matching any run of characters within a segment, e.g. projects/42/* or projects/42/file-*
+// - `**` as a whole segment, matching any number of segments, including none, e.g. projects/42/**
+// - `{name}`, matching any value of a placeholder within a segment; {self} is the user's subject ID, and any other
+// name is resolved as a marker added with RegisterMarker, e.g. projects/{team}/**
+type Pattern struct {
+	source   string
+	segments []segment
+	names    []string
+}
+
+type segment struct {
+	// any is set for a ** segment, which matches any number of segments.
+	any   bool
+	parts []part
+}
+
+type partKind int
+
+const (
+	partLiteral partKind = iota
+	partStar
+	partPlaceholder
+)
+
+type part struct {
+	kind partKind
+	text string
+
+	// name is the index of a placeholder's name in Pattern.names.
+	name int
+}
+
+// IsPattern checks if a subject value should be treated as a Pattern rather than a literal ID.
+// Wildcard on its own is not a pattern.
+func IsPattern(value string) bool {
+	return value != Wildcard && strings.ContainsAny(value, "*{}")
+}
+
+// CompilePattern parses a subject pattern, returning an error if its syntax is invalid.
+func CompilePattern(source string) (*Pattern, error) {
+	p := &Pattern{source: source}
+
+	for _, raw := range strings.Split(source, "/") {
+		if raw == "**" {
+			// consecutive ** segments match the same as one.
+			if n := len(p.segments); n == 0 || !p.segments[n-1].any {
+				p.segments = append(p.segments, segment{any: true})
+			}
+
+			continue
+		}
+
+		seg, err := p.compileSegment(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rbac: invalid subject pattern %q: %w", source, err)
+		}
+
+		p.segments = append(p.segments, seg)
+	}
+
+	return p, nil
+}
+
+func (p *Pattern) compileSegment(raw string) (segment, error) {
+	seg := segment{}
+
+	for i := 0; i < len(raw); {
+		switch raw[i] {
+		case '*':
+			if i+1 < len(raw) && raw[i+1] == '*' {
+				return seg, fmt.Errorf("** must be a whole segment")
+			}
+
+			seg.parts = append(seg.parts, part{kind: partStar})
+			i++
+
+		case '{':
+			end := strings.IndexByte(raw[i:], '}')
+			if end < 0 {
+				return seg, fmt.Errorf("unclosed {")
+			}
+
+			name := raw[i+1 : i+end]
+			if !validName(name) {
+				return seg, fmt.Errorf("invalid placeholder {%s}", name)
+			}
+
+			seg.parts = append(seg.parts, part{kind: partPlaceholder, text: name, name: p.addName(name)})
+			i += end + 1
+
+		case '}':
+			return seg, fmt.Errorf("unexpected }")
+
+		default:
+			end := strings.IndexAny(raw[i:], "*{}")
+			if end < 0 {
+				end = len(raw) - i
+			}
+
+			seg.parts = append(seg.parts, part{kind: partLiteral, text: raw[i : i+end]})
+			i += end
+		}
+	}
+
+	return seg, nil
+}
+
+// addName adds a placeholder's name to the pattern, if it isn't there already, and returns its index.
+func (p *Pattern) addName(name string) int {
+	for i, existing := range p.names {
+		if existing == name {
+			return i
+		}
+	}
+
+	p.names = append(p.names, name)
+
+	return len(p.names) - 1
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns the source of the pattern.
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Placeholders returns the name of every placeholder in the pattern, e.g. self for projects/{self}/**.
+func (p *Pattern) Placeholders() []string {
+	return append([]string(nil), p.names...)
+}
+
+// Match checks if value matches the pattern. vars returns the possible values of a placeholder by name, and is called
+// at most once for each placeholder, when it is first needed, as resolving a marker can be expensive.
+//
+// Matching takes time proportional to the number of segments in the pattern and in value, however many * and **
+// the pattern has, so patterns loaded at runtime can't make checks take exponential time.
+func (p *Pattern) Match(value string, vars func(name string) []string) bool {
+	resolved := make([][]string, len(p.names))
+	done := make([]bool, len(p.names))
+
+	lookup := func(i int) []string {
+		if !done[i] {
+			resolved[i], done[i] = vars(p.names[i]), true
+		}
+
+		return resolved[i]
+	}
+
+	return matchSegments(p.segments, strings.Split(value, "/"), lookup)
+}
+
+// matchSegments checks if values match segments, working backwards so that each suffix of segments is only matched
+// against each suffix of values once. matched[j] holds whether segments[i+1:] match values[j:].
+func matchSegments(segments []segment, values []string, vars func(name int) []string) bool {
+	matched := make([]bool, len(values)+1)
+	matched[len(values)] = true
+
+	next := make([]bool, len(values)+1)
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		for j := len(values); j >= 0; j-- {
+			switch {
+			case segments[i].any:
+				// ** matches no segments, or the segment at j and any number after it.
+				next[j] = matched[j] || (j < len(values) && next[j+1])
+			case j == len(values):
+				next[j] = false
+			default:
+				next[j] = matched[j+1] && matchParts(segments[i].parts, values[j], vars)
+			}
+		}
+
+		matched, next = next, matched
+	}
+
+	return matched[0]
+}
+
+// matchParts checks if a single segment of a value matches parts, in the same way as matchSegments.
+// matched[k] holds whether parts[i+1:] match value[k:].
+func matchParts(parts []part, value string, vars func(name int) []string) bool {
+	matched := make([]bool, len(value)+1)
+	matched[len(value)] = true
+
+	next := make([]bool, len(value)+1)
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		var values []string
+		if parts[i].kind == partPlaceholder {
+			values = vars(parts[i].name)
+		}
+
+		for k := len(value); k >= 0; k-- {
+			switch parts[i].kind {
+			case partStar:
+				next[k] = matched[k] || (k < len(value) && next[k+1])
+
+			case partPlaceholder:
+				next[k] = false
+
+				for _, v := range values {
+					if v != "" && strings.HasPrefix(value[k:], v) && matched[k+len(v)] {
+						next[k] = true
+
+						break
+					}
+				}
+
+			default:
+				text := parts[i].text
+				next[k] = strings.HasPrefix(value[k:], text) && matched[k+len(text)]
+			}
+		}
+
+		matched, next = next, matched
+	}
+
+	return matched[0]
+}
+
+// patterns holds patterns compiled by CachedPattern, keyed by source.
+var patterns sync.Map
+
+// CachedPattern works like CompilePattern, but only compiles each distinct pattern once.
+func CachedPattern(source string) (*Pattern, error) {
+	if p, ok := patterns.Load(source); ok {
+		return p.(*Pattern), nil
+	}
+
+	p, err := CompilePattern(source)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(source, p)
+
+	return p, nil
+}
@@ -0,0 +1,125 @@
+package subject
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatternMatch(t *testing.T) {
+	vars := func(name string) []string {
+		switch name {
+		case "self":
+			return []string{"u1"}
+		case "team":
+			return []string{"t1", "t2"}
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"projects/42/*", "projects/42/files", true},
+		{"projects/42/*", "projects/42/files/7", false},
+		{"projects/42/*", "projects/42", false},
+		{"projects/*/files", "projects/42/files", true},
+		{"projects/42/file-*", "projects/42/file-3", true},
+		{"projects/42/file-*", "projects/42/doc-3", false},
+		{"projects/42/**", "projects/42", true},
+		{"projects/42/**", "projects/42/a/b/c", true},
+		{"projects/**/files", "projects/files", true},
+		{"projects/**/files", "projects/a/b/files", true},
+		{"projects/**/files", "projects/a/b/docs", false},
+		{"**", "anything/at/all", true},
+		{"projects/**/**/files", "projects/a/files", true},
+		{"projects/{self}/**", "projects/u1/x", true},
+		{"projects/{self}/**", "projects/u2/x", false},
+		{"teams/{team}/*", "teams/t2/doc", true},
+		{"teams/{team}/*", "teams/t3/doc", false},
+		{"teams/{team}-*/x", "teams/t1-a/x", true},
+		{"teams/{unknown}/x", "teams/t1/x", false},
+		{"*a*b", "xaxb", true},
+		{"*a*b", "xaxc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.value, func(t *testing.T) {
+			p, err := CompilePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompilePattern() error = %v", err)
+			}
+
+			if got := p.Match(tt.value, vars); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, pattern := range []string{"a/**b", "a/{x", "a/x}", "a/{}", "a/{x y}"} {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("CompilePattern(%q) error = nil, want an error", pattern)
+		}
+	}
+}
+
+func TestPatternMatchIsNotExponential(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+	}{
+		{strings.Repeat("**/", 20) + "x", strings.Repeat("a/", 500) + "y"},
+		{strings.Repeat("*/**/", 20) + "x", strings.Repeat("a/", 500) + "y"},
+		{strings.Repeat("*a", 20) + "b", strings.Repeat("a", 500)},
+	}
+
+	for _, tt := range tests {
+		p, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+
+		if p.Match(tt.value, func(string) []string { return nil }) {
+			t.Errorf("Match(%q) = true, want false", tt.pattern)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Match(%q) took %s", tt.pattern, elapsed)
+		}
+	}
+}
+
+func TestPatternResolvesPlaceholdersOnce(t *testing.T) {
+	p, err := CompilePattern("**/{team}-{team}/{self}/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := map[string]int{}
+	vars := func(name string) []string {
+		calls[name]++
+
+		return []string{"t1", "t2"}
+	}
+
+	if p.Match(strings.Repeat("a/", 50)+"x", vars) {
+		t.Error("Match() = true, want false")
+	}
+
+	for name, n := range calls {
+		if n != 1 {
+			t.Errorf("resolved {%s} %d times, want once", name, n)
+		}
+	}
+
+	if !p.Match("a/t1-t2/t2/b", vars) {
+		t.Error("Match() = false, want true")
+	}
+}
@@ -2,17 +2,21 @@
 //
 // Most commonly used are Wildcard and Self; the former means the permission applies for any subject.
 // The latter, Self, means the resolved subject ID (usually a user's database ID, or similar) is checked.
+// Further markers, such as a user's team, can be added with RegisterMarker.
 package subject
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 )
 
 const (
 	// Wildcard should be used when a role should have access to any subject via a permission.
 	Wildcard = "*"
 
-	// Self should be used when a permission is only granted to a resource that matches the user's ID.
+	// Self should be used when a permission is only granted to a resource that matches the user's ID,
+	// or to a resource owned by the user; see OwnedBy.
 	Self = "rbac.self"
 )
 
@@ -22,46 +26,150 @@ const (
 // The constant `Self` is always replaced with the expected SubjectID of the user in calling functions.
 // Calling Matches(Self, any) is not valid on its own.
 //
-// Match values are always converted to a string, in priority order:
+// Match values are always converted to a string with Convert, in priority order:
 // - Strings are passed as-is.
 // - Integers and floats are converted to strings.
 // - Anything with an `RBACSubjectID() string` method uses the result of that.
 // - If the given type has a fmt.Stringer method, we use the result.
-// - If nothing else is valid, we raise a panic.
+// - If nothing else is valid, the values never match; in strict mode, we raise a panic instead.
 func Matches(expected, actual any) bool {
 	if expected == Wildcard {
 		return true
 	}
 
-	return convert(expected) == convert(actual)
+	e, ok := convert(expected)
+	if !ok {
+		return false
+	}
+
+	a, ok := convert(actual)
+
+	return ok && e == a
 }
 
-// convert takes a subject and converts it to a string via most means available.
-func convert(sub any) string {
+// ErrUnsupported is returned by Convert when a subject can't be converted to a string.
+var ErrUnsupported = errors.New("rbac: subject doesn't implement RBACSubjectID, or can't coerce to string")
+
+// strict is set by SetStrict.
+var strict atomic.Bool
+
+// SetStrict makes matching panic when a subject can't be converted, rather than treating it as a mismatch.
+// It is intended for tests, so that passing the wrong value to a permission check fails loudly.
+// Permission checks only panic for subjects that Validate rejects.
+func SetStrict(enabled bool) {
+	strict.Store(enabled)
+}
+
+// Strict reports whether strict mode has been enabled with SetStrict.
+func Strict() bool {
+	return strict.Load()
+}
+
+// Convert takes a subject and converts it to a string via most means available.
+// If the subject is an incompatible type (struct, slice/array, complex, uintptr, chan), an error wrapping
+// ErrUnsupported is returned.
+func Convert(sub any) (string, error) {
 	type rbacSubject interface {
 		RBACSubjectID() string
 	}
 
 	if s, ok := sub.(rbacSubject); ok {
-		return s.RBACSubjectID()
+		return s.RBACSubjectID(), nil
 	}
 
 	switch s := sub.(type) {
 	case string:
-		return s
+		return s, nil
 
 	case int, int8, int16, int32, int64,
 		uint, uint8, uint16, uint32, uint64,
 		float32, float64:
-		return fmt.Sprintf("%v", s)
-
-	case rbacSubject:
-		return s.RBACSubjectID()
+		return fmt.Sprintf("%v", s), nil
 
 	case fmt.Stringer:
-		return s.String()
+		return s.String(), nil
+	}
+
+	return "", fmt.Errorf("%w: %T given", ErrUnsupported, sub)
+}
+
+// Validate checks that a subject can be used in a permission check: either it can be converted with Convert,
+// or it is only useful for its owners or attributes, such as a document implementing RBACOwnerID.
+func Validate(sub any) error {
+	type usable interface {
+		RBACOwnerID() string
+	}
+
+	type usableMany interface {
+		RBACOwnerIDs() []string
+	}
+
+	type attributed interface {
+		RBACAttributes() map[string]any
+	}
+
+	switch sub.(type) {
+	case usable, usableMany, attributed:
+		return nil
+	}
+
+	_, err := Convert(sub)
+
+	return err
+}
+
+// convert works like Convert, but panics in strict mode, and otherwise reports whether the conversion worked.
+func convert(sub any) (string, bool) {
+	s, err := Convert(sub)
+	if err != nil {
+		if Strict() {
+			panic(err.Error())
+		}
+
+		return "", false
+	}
+
+	return s, true
+}
+
+// Attributes returns the attributes of a subject for grant conditions, where they are available as resource.<key>.
+// Subjects expose attributes with an `RBACAttributes() map[string]any` method; anything else has none.
+func Attributes(sub any) map[string]any {
+	type attributed interface {
+		RBACAttributes() map[string]any
+	}
+
+	if s, ok := sub.(attributed); ok {
+		return s.RBACAttributes()
+	}
+
+	return nil
+}
+
+// OwnedBy checks if id owns sub, for subjects that expose their owners with an `RBACOwnerID() string` or
+// `RBACOwnerIDs() []string` method. If sub has neither, ok is false, and sub itself should be compared against id.
+func OwnedBy(sub any, id string) (owned, ok bool) {
+	type owner interface {
+		RBACOwnerID() string
+	}
+
+	type owners interface {
+		RBACOwnerIDs() []string
+	}
+
+	switch s := sub.(type) {
+	case owner:
+		return s.RBACOwnerID() == id, true
+
+	case owners:
+		for _, ownerID := range s.RBACOwnerIDs() {
+			if ownerID == id {
+				return true, true
+			}
+		}
+
+		return false, true
 	}
 
-	// if we're given an incompatible type (struct, slice/array, complex, uintptr, chan), bail out.
-	panic(fmt.Sprintf("rbac: subject doesn't implement RBACSubjectID, or can't coerce to string: %T given", sub))
+	return false, false
 }
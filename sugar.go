@@ -14,38 +14,24 @@ func log(ctx context.Context, msg string, kv ...any) {
 }
 
 // Can uses the current context values to determine if an action can be taken.
+// A deny grant on any of the user's roles overrides allows from every other role.
 //
 // Usage: if rbac.Can(ctx, permissions.SpecificationCreate) {}
 func Can(ctx context.Context, perm Permission, subjects ...any) bool {
-	result := false
-
-	for _, role := range Roles(ctx) {
-		if role.Can(ctx, perm, subjects...) {
-			result = true
-		}
-	}
-
-	log(ctx, "checking permissions",
-		"rbac.permission.id", perm.ID,
-		"rbac.result", result)
-
-	return result
+	return defaultEnforcer.Can(ctx, perm, subjects...)
 }
 
 // Roles returns a list of roleLookup in the current context.
 // The roleLookup must have been set up globally.
 func Roles(ctx context.Context) []Role {
-	if user := User(ctx); user != nil {
-		roles := state.rolesByID(user.RBACRoles())
-
-		if len(roles) == 0 {
-			log(ctx, "no roles present on subject", "rbac.subject.id", user.RBACSubjectID())
-		}
-
-		return roles
-	}
+	return defaultEnforcer.Roles(ctx)
+}
 
-	return nil
+// RoleByID returns the role registered with SetDefaultRoles with the given ID, with its inherited permissions resolved.
+//
+// Usage: admin, ok := rbac.RoleByID(roles.Admin.ID)
+func RoleByID(id string) (Role, bool) {
+	return defaultEnforcer.RoleByID(id)
 }
 
 func User(ctx context.Context) values.User {
@@ -2,6 +2,7 @@ package values
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-logr/logr"
 )
@@ -12,12 +13,74 @@ type contextKeyType struct{}
 // contextKey is used as a key for pulling values out of a context.
 var contextKey contextKeyType
 
+// scopeKeyType is an unexported type for storing the active scope with context.WithValue.
+type scopeKeyType struct{}
+
+var scopeKey scopeKeyType
+
+// subjectKeyType is an unexported type for storing a bare subject ID with context.WithValue.
+type subjectKeyType struct{}
+
+var subjectKey subjectKeyType
+
 // User is an interface used to provide a subject ID and a slice of roles.
 type User interface {
 	RBACSubjectID() string
 	RBACRoles() []string
 }
 
+// AttributedUser is an optional extension of User, exposing attributes that grant conditions can refer to as user.<key>.
+type AttributedUser interface {
+	User
+	RBACAttributes() map[string]any
+}
+
+// Grant assigns a role to a user for a window of time.
+// A zero NotBefore or NotAfter leaves that side of the window open.
+type Grant struct {
+	RoleID    string
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	// Scope limits the grant to a single scope, such as a tenant; see WithScope. Empty grants are global.
+	Scope string
+}
+
+// Active checks if the grant is valid at now. NotBefore is inclusive, and NotAfter is exclusive.
+func (g Grant) Active(now time.Time) bool {
+	if !g.NotBefore.IsZero() && now.Before(g.NotBefore) {
+		return false
+	}
+
+	return g.NotAfter.IsZero() || now.Before(g.NotAfter)
+}
+
+// GrantedUser is an optional extension of User, for roles that are only held for a window of time.
+// Roles from active grants are added to those from RBACRoles.
+type GrantedUser interface {
+	User
+	RBACRoleGrants() []Grant
+}
+
+// ScopedUser is an optional extension of User, for users that hold different roles in each scope, such as a tenant.
+// While a scope is active, roles come from RBACScopedRoles instead of RBACRoles.
+type ScopedUser interface {
+	User
+	RBACScopedRoles(scope string) []string
+}
+
+// WithScope sets the active scope, such as a tenant ID, for permission checks made with the returned context.
+func WithScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, scopeKey, scope)
+}
+
+// ScopeFromContext returns the active scope, if one was set with WithScope.
+func ScopeFromContext(ctx context.Context) (string, bool) {
+	scope, ok := ctx.Value(scopeKey).(string)
+
+	return scope, ok
+}
+
 // FromContext grabs a user from a context, if it has been set as a value.
 func FromContext(ctx context.Context) User {
 	v := ctx.Value(contextKey)
@@ -34,6 +97,19 @@ func Embed(ctx context.Context, user User) context.Context {
 	return context.WithValue(ctx, contextKey, user)
 }
 
+// EmbedSubjectID embeds a bare subject ID into the current request context, for middleware that authenticates a user
+// without loading their roles. rbac.EmbedAssignedUser turns it into a User with roles from an rbac.AssignmentStore.
+func EmbedSubjectID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, subjectKey, id)
+}
+
+// SubjectIDFromContext returns a bare subject ID, if one was set with EmbedSubjectID.
+func SubjectIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(subjectKey).(string)
+
+	return id, ok
+}
+
 func SubjectFromContext(ctx context.Context) (string, bool) {
 	if u := FromContext(ctx); u != nil {
 		return u.RBACSubjectID(), true
@@ -0,0 +1,144 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is how often WatchPolicyFile checks for changes, unless WatchOptions.Interval is set.
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchOptions configures WatchPolicyFile.
+type WatchOptions struct {
+	// Enforcer receives reloaded roles. If nil, the default Enforcer is used.
+	Enforcer *Enforcer
+
+	// Interval is how often the file is checked for changes. If zero, DefaultWatchInterval is used.
+	Interval time.Duration
+
+	// OnReload is called after every attempt to reload a changed policy, with a nil error if the new roles are in use.
+	// On error, the previous roles are kept.
+	OnReload func(err error)
+}
+
+// errEmptyPolicy is returned for a policy file without roles, which is almost certainly a mistake when reloading.
+var errEmptyPolicy = errors.New("rbac: policy has no roles")
+
+// WatchPolicyFile loads roles from the policy file at path, then polls it for changes until ctx is done.
+//
+// The initial load happens before WatchPolicyFile returns, and its error is returned directly. Afterwards, a change is
+// only applied once the file's content has stayed the same for a whole interval, so a file that is still being written
+// isn't loaded half-way through. Changed policies are validated with LoadPolicy, and policies without roles are
+// rejected; in either case the Enforcer keeps its previous roles, and the error is passed to OnReload.
+func WatchPolicyFile(ctx context.Context, path string, opts WatchOptions) error {
+	if opts.Enforcer == nil {
+		opts.Enforcer = defaultEnforcer
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+
+	w := &policyWatcher{path: path, opts: opts}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := w.apply(content); err != nil {
+		return err
+	}
+
+	go w.run(ctx)
+
+	return nil
+}
+
+type policyWatcher struct {
+	path string
+	opts WatchOptions
+
+	// applied is the hash of the policy in use, and pending the hash of changed content waiting to settle.
+	applied [sha256.Size]byte
+	pending [sha256.Size]byte
+
+	// failed is the hash of the last content that couldn't be loaded, so each failure is only reported once.
+	failed [sha256.Size]byte
+}
+
+func (w *policyWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll reads the policy file, reloading it if it has changed and settled since the last poll.
+func (w *policyWatcher) poll() {
+	content, err := os.ReadFile(w.path)
+
+	sum := sha256.Sum256(content)
+	if err != nil {
+		// the file may briefly be missing while it's replaced, so only report it once it has settled.
+		sum = sha256.Sum256([]byte(err.Error()))
+	}
+
+	switch {
+	case sum == w.applied:
+		w.failed = [sha256.Size]byte{}
+	case sum == w.failed:
+		// this content has already been reported as broken.
+	case sum != w.pending:
+		// the content changed since the last poll, so wait for it to settle.
+	case err != nil:
+		w.report(sum, err)
+	default:
+		w.report(sum, w.apply(content))
+	}
+
+	w.pending = sum
+}
+
+// apply loads and validates a policy, replacing the Enforcer's roles if it's valid.
+func (w *policyWatcher) apply(content []byte) error {
+	roles, err := LoadPolicy(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+
+	if len(roles) == 0 {
+		return fmt.Errorf("%s: %w", w.path, errEmptyPolicy)
+	}
+
+	if err := w.opts.Enforcer.ReplaceRoles(roles); err != nil {
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+
+	w.applied = sha256.Sum256(content)
+
+	return nil
+}
+
+// report passes the result of a reload to OnReload, remembering failures so each is only reported once.
+func (w *policyWatcher) report(sum [sha256.Size]byte, err error) {
+	if err != nil {
+		w.failed = sum
+	}
+
+	if w.opts.OnReload != nil {
+		w.opts.OnReload(err)
+	}
+}
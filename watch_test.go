@@ -0,0 +1,98 @@
+package rbac
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	viewPolicy = "permissions: {view: {}, edit: {}}\nroles:\n  r:\n    permissions: [view]\n"
+	editPolicy = "permissions: {view: {}, edit: {}}\nroles:\n  r:\n    permissions: [edit]\n"
+)
+
+func TestPolicyWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac.yaml")
+	writePolicy(t, path, viewPolicy)
+
+	var reloads []error
+
+	e := NewEnforcer(nil)
+	w := &policyWatcher{path: path, opts: WatchOptions{
+		Enforcer: e,
+		OnReload: func(err error) { reloads = append(reloads, err) },
+	}}
+
+	if err := w.apply([]byte(viewPolicy)); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	ctx := userContext("u1", "r")
+	view, edit := Permission{ID: "view"}, Permission{ID: "edit"}
+
+	// a change is only applied once it has stayed the same for a whole poll.
+	writePolicy(t, path, editPolicy)
+	w.poll()
+
+	if !e.Can(ctx, view) || len(reloads) != 0 {
+		t.Fatalf("policy reloaded before it settled: reloads = %v", reloads)
+	}
+
+	w.poll()
+
+	if e.Can(ctx, view) || !e.Can(ctx, edit) || len(reloads) != 1 || reloads[0] != nil {
+		t.Fatalf("policy not reloaded after it settled: reloads = %v", reloads)
+	}
+
+	// a broken policy is reported once, and the previous roles are kept.
+	writePolicy(t, path, "roles:\n  r:\n    permissions: [missing]\n")
+
+	for i := 0; i < 3; i++ {
+		w.poll()
+	}
+
+	if !e.Can(ctx, edit) || len(reloads) != 2 || reloads[1] == nil {
+		t.Fatalf("broken policy: Can() = %v, reloads = %v", e.Can(ctx, edit), reloads)
+	}
+
+	// a policy without roles is rejected.
+	writePolicy(t, path, "permissions: {view: {}}\n")
+	w.poll()
+	w.poll()
+
+	if !e.Can(ctx, edit) || len(reloads) != 3 || reloads[2] == nil {
+		t.Fatalf("empty policy: Can() = %v, reloads = %v", e.Can(ctx, edit), reloads)
+	}
+}
+
+func TestWatchPolicyFileInitialLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rbac.yaml")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	e := NewEnforcer(nil)
+
+	if err := WatchPolicyFile(ctx, path, WatchOptions{Enforcer: e}); err == nil {
+		t.Error("WatchPolicyFile() error = nil for a missing file")
+	}
+
+	writePolicy(t, path, viewPolicy)
+
+	if err := WatchPolicyFile(ctx, path, WatchOptions{Enforcer: e}); err != nil {
+		t.Fatalf("WatchPolicyFile() error = %v", err)
+	}
+
+	if !e.Can(userContext("u1", "r"), Permission{ID: "view"}) {
+		t.Error("Can() = false after the initial load, want true")
+	}
+}
+
+func writePolicy(t *testing.T, path, policy string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+}